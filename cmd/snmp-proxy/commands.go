@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+	"go.uber.org/zap"
+)
+
+func runCommand(config *Configuration, args []string) {
+	switch args[0] {
+	case "export-mib-bundle":
+		if len(args) != 2 {
+			config.Logger.Fatal("usage: export-mib-bundle <file>")
+		}
+
+		exportMibBundle(config, args[1])
+	default:
+		config.Logger.Fatalw("unknown command", "command", args[0])
+	}
+}
+
+func exportMibBundle(config *Configuration, path string) {
+	objects, err := mib.NewNetsnmpMibParser(config.Logger, config.Snmp.StrictMibParsing).Parse()
+	if err != nil {
+		config.Logger.Fatalw("mib parser error: ", zap.Error(err))
+	}
+
+	if len(objects) == 0 {
+		config.Logger.Fatal("no MIB objects were parsed, make sure to use a binary built with net-snmp support")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		config.Logger.Fatalw("failed to create MIB bundle file", zap.Error(err))
+	}
+
+	if err = mib.WriteBundle(file, objects); err == nil {
+		err = file.Close()
+	}
+
+	if err != nil {
+		config.Logger.Fatalw("failed to write MIB bundle", zap.Error(err))
+	}
+
+	config.Logger.Infow("MIB bundle exported", "path", path, "objects", len(objects))
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/TheZeroSlave/zapsentry"
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -17,6 +18,16 @@ type Configuration struct {
 	Api struct {
 		Listen            string
 		SocketPermissions os.FileMode // only ever used when Listen is a Unix socket
+		Admission         snmpproxy.AdmissionLimits
+		Batch             snmpproxy.BatchLimits // zero MaxConcurrency disables the batch requests
+		Jobs              struct {
+			Enabled               bool // enables the asynchronous API requests
+			snmpproxy.JobSettings `mapstructure:",squash"`
+		}
+	}
+	Cache struct {
+		Ttls       []snmpproxy.CacheTtl // no results are cached without any TTLs
+		MaxEntries uint                 // the least recently used results are evicted above it, zero means the default
 	}
 	Common struct {
 		Debug      bool
@@ -25,13 +36,30 @@ type Configuration struct {
 			Dsn string
 		}
 	}
+	Formatting struct {
+		Overrides []snmpproxy.FormatOverride
+		Profiles  map[string][]snmpproxy.FormatOverride // profile name -> overrides
+	}
 	Metrics struct {
 		Listen string
 	}
 	Snmp struct {
-		MaxTimeoutSeconds uint
-		MaxRetries        uint8
-		StrictMibParsing  bool
+		MinTimeoutMilliseconds uint
+		MaxTimeoutSeconds      uint
+		MaxRetries             uint8
+		MaxParallelism         uint8 // maximum allowed field parallel of the walks, zero disallows the parallel walks
+		StrictMibParsing       bool
+		MibBundle              string // when set, MIBs are loaded from this bundle instead of being parsed by net-snmp
+		PartialResults         string // what to return when the request deadline expires, see snmpproxy.PartialResultPolicy
+		Limits                 snmpproxy.WalkLimits
+		WalkStrategy           string // strategy of the walks that don't specify any, see snmpproxy.WalkStrategy
+		Targets                snmpproxy.TargetLimits
+		CircuitBreaker         snmpproxy.CircuitBreakerSettings
+		ConnectionPool         snmpproxy.ConnectionPoolSettings
+		// MaxDurationSeconds limits a single SNMP request with all its retries, as allowed by the retry policy
+		// (MaxTimeoutSeconds * (MaxRetries + 1) by default)
+		MaxDurationSeconds uint
+		RetryPolicies      snmpproxy.RetryPolicies
 	}
 	Logger *zap.SugaredLogger
 }
@@ -73,8 +101,10 @@ func NewConfiguration() *Configuration {
 		"the working directory and the directory of this executable)")
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [options]\nAvailable options:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] [command]\nAvailable options:\n", os.Args[0])
 		flag.PrintDefaults()
+		fmt.Fprint(os.Stderr, "Available commands:\n  export-mib-bundle <file>\n    \tparse the MIBs and export "+
+			"the result into a bundle that can be loaded via the Snmp.MibBundle option\n")
 	}
 	flag.Parse()
 
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"flag"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/grongor/go-snmp-proxy/metrics"
 	"github.com/grongor/go-snmp-proxy/snmpproxy"
@@ -15,6 +17,12 @@ import (
 func main() {
 	config := NewConfiguration()
 
+	if flag.NArg() != 0 {
+		runCommand(config, flag.Args())
+
+		return
+	}
+
 	if config.Common.Panicwatch {
 		startPanicwatch(config.Logger)
 	}
@@ -24,35 +32,124 @@ func main() {
 	}
 
 	validator := snmpproxy.NewRequestValidator(config.Snmp.MaxTimeoutSeconds, config.Snmp.MaxRetries)
+	validator.SetMinTimeout(time.Duration(config.Snmp.MinTimeoutMilliseconds) * time.Millisecond)
+	validator.SetMaxWalkParallelism(config.Snmp.MaxParallelism)
+	validator.SetRetryPolicies(config.Snmp.RetryPolicies)
+
+	if config.Snmp.MaxDurationSeconds != 0 {
+		validator.SetMaxDuration(time.Duration(config.Snmp.MaxDurationSeconds) * time.Second)
+	}
+
+	if err := config.Api.Admission.Validate(); err != nil {
+		config.Logger.Fatalw("invalid config option Api.Admission", zap.Error(err))
+	}
+
+	if err := config.Snmp.RetryPolicies.Validate(); err != nil {
+		config.Logger.Fatalw("invalid config option Snmp.RetryPolicies", zap.Error(err))
+	}
 
-	mibParser := mib.NewNetsnmpMibParser(config.Logger, config.Snmp.StrictMibParsing)
+	var mibParser mib.Parser
+	if config.Snmp.MibBundle != "" {
+		mibParser = mib.NewBundleMibParser(config.Snmp.MibBundle)
+	} else {
+		mibParser = mib.NewNetsnmpMibParser(config.Logger, config.Snmp.StrictMibParsing)
+	}
+
+	valueFormatter := snmpproxy.NewValueFormatter(mib.NewDataProvider(nil))
 
-	displayHints, err := mibParser.Parse()
+	formatOverrides, err := snmpproxy.NewFormatOverrides(config.Formatting.Overrides, config.Formatting.Profiles)
 	if err != nil {
+		config.Logger.Fatalw("invalid formatting overrides", zap.Error(err))
+	}
+
+	valueFormatter.SetFormatOverrides(formatOverrides)
+
+	mibApi := snmpproxy.NewMibApi(mib.NewDataProvider(nil))
+	mibReloader := snmpproxy.NewMibReloader(mibParser, config.Logger, valueFormatter, mibApi)
+
+	if err := mibReloader.Reload(); err != nil {
 		config.Logger.Fatalw("mib parser error: ", zap.Error(err))
 	}
 
-	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(displayHints)))
+	partialResultPolicy, err := snmpproxy.NewPartialResultPolicy(config.Snmp.PartialResults)
+	if err != nil {
+		config.Logger.Fatalw("invalid config option Snmp.PartialResults", zap.Error(err))
+	}
+
+	walkStrategy, err := snmpproxy.NewWalkStrategy(config.Snmp.WalkStrategy)
+	if err != nil {
+		config.Logger.Fatalw("invalid config option Snmp.WalkStrategy", zap.Error(err))
+	}
+
+	requester := snmpproxy.NewGosnmpRequester(valueFormatter)
+	requester.SetPartialResultPolicy(partialResultPolicy)
+	requester.SetWalkLimits(config.Snmp.Limits)
+	requester.SetWalkStrategy(walkStrategy)
+	requester.SetTargetLimits(config.Snmp.Targets)
+	requester.SetAdmissionLimits(config.Api.Admission)
+	requester.SetCircuitBreaker(config.Snmp.CircuitBreaker)
+	requester.SetConnectionPool(config.Snmp.ConnectionPool)
+	requester.SetRetryPolicies(config.Snmp.RetryPolicies)
+
+	var apiRequester snmpproxy.Requester = snmpproxy.NewSingleFlightRequester(requester)
+	if len(config.Cache.Ttls) != 0 {
+		cachingRequester := snmpproxy.NewCachingRequester(apiRequester, config.Cache.Ttls)
+		cachingRequester.SetMaxEntries(config.Cache.MaxEntries)
+
+		apiRequester = cachingRequester
+	}
 
 	apiListener := snmpproxy.NewApiListener(
 		validator,
-		requester,
+		apiRequester,
 		config.Logger,
 		config.Api.Listen,
 		config.Api.SocketPermissions,
 	)
 
-	err = apiListener.Start()
-	if err != nil {
+	apiListener.Handle("/admin/mib/reload", mibReloader)
+	apiListener.Handle("/admin/walk-settings", requester.WalkMemory())
+	apiListener.Handle("/mib/", mibApi)
+
+	if config.Api.Batch.MaxConcurrency != 0 {
+		apiListener.Handle("/snmp-proxy/batch", apiListener.BatchHandler(config.Api.Batch))
+	}
+
+	if config.Api.Jobs.Enabled {
+		if err := config.Api.Jobs.Validate(); err != nil {
+			config.Logger.Fatalw("invalid config option Api.Jobs", zap.Error(err))
+		}
+
+		jobs := apiListener.JobsHandler(config.Api.Jobs.JobSettings)
+		apiListener.Handle("/snmp-proxy/jobs", jobs)
+		apiListener.Handle("/snmp-proxy/jobs/{id}", jobs)
+	}
+
+	if circuitBreaker := requester.CircuitBreaker(); circuitBreaker != nil {
+		apiListener.Handle("/admin/circuits", circuitBreaker)
+	}
+
+	if err := apiListener.Start(); err != nil {
 		config.Logger.Fatalw("failed to start API listener", zap.Error(err))
 	}
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
 
-	<-signals
-	config.Logger.Info("received shutdown signal, exiting")
-	apiListener.Close()
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			go func() {
+				_ = mibReloader.Reload() // errors are already logged by the reloader
+			}()
+
+			continue
+		}
+
+		config.Logger.Info("received shutdown signal, exiting")
+		apiListener.Close()
+
+		return
+	}
 }
 
 func startPanicwatch(logger *zap.SugaredLogger) {
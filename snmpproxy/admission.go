@@ -0,0 +1,148 @@
+package snmpproxy
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// AdmissionLimits limit the SNMP operations executed at once (every get or walk, and every further worker
+// of the parallel walks, uses its own socket), so that a burst of requests doesn't exhaust the sockets (or
+// the ephemeral ports) of the proxy. The operations over the limit wait in a bounded queue. A single API request may
+// need multiple operations at once, so MaxWait must be set for them not to wait for each other forever.
+type AdmissionLimits struct {
+	MaxConcurrent uint          // zero means unlimited
+	MaxQueued     uint          // operations waiting for admission, the following ones are rejected right away
+	MaxWait       time.Duration // queued operations are rejected after waiting this long, required by MaxConcurrent
+}
+
+// Validate checks that MaxWait is set if the operations are limited.
+func (l AdmissionLimits) Validate() error {
+	if l.MaxConcurrent != 0 && l.MaxWait <= 0 {
+		return errors.New("MaxWait must be set if MaxConcurrent is, the operations could wait for each other forever")
+	}
+
+	return nil
+}
+
+// AdmissionError is returned for the SNMP operations that weren't admitted, it wraps either ErrQueueFull
+// or ErrAdmissionTimeout.
+type AdmissionError struct {
+	Err        error
+	RetryAfter time.Duration // when the client may try again, see RetryAfterHeader
+}
+
+func (e *AdmissionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AdmissionError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfterHeader returns the value of the Retry-After header (in whole seconds) of the rejected API request.
+func (e *AdmissionError) RetryAfterHeader() string {
+	return strconv.Itoa(max(1, int(math.Ceil(e.RetryAfter.Seconds()))))
+}
+
+// admissionController admits the SNMP operations, see AdmissionLimits.
+type admissionController struct {
+	limits     AdmissionLimits
+	slots      chan struct{}
+	queued     atomic.Int64
+	inFlight   prometheus.Gauge
+	queueDepth prometheus.Gauge
+	rejected   *prometheus.CounterVec
+}
+
+// acquire admits the operation, possibly after waiting in the queue. It returns the function that releases the slot
+// of the operation.
+func (c *admissionController) acquire(ctx context.Context) (func(), error) {
+	if c == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return c.admitted(), nil
+	default:
+	}
+
+	if c.queued.Add(1) > int64(c.limits.MaxQueued) {
+		c.queued.Add(-1)
+
+		return nil, c.reject(ErrQueueFull)
+	}
+
+	c.queueDepth.Inc()
+
+	defer func() {
+		c.queued.Add(-1)
+		c.queueDepth.Dec()
+	}()
+
+	var timeout <-chan time.Time
+
+	if c.limits.MaxWait != 0 {
+		timer := time.NewTimer(c.limits.MaxWait)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return c.admitted(), nil
+	case <-timeout:
+		return nil, c.reject(ErrAdmissionTimeout)
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
+func (c *admissionController) admitted() func() {
+	c.inFlight.Inc()
+
+	return func() {
+		<-c.slots
+		c.inFlight.Dec()
+	}
+}
+
+func (c *admissionController) reject(err error) error {
+	c.rejected.WithLabelValues(ErrorCode(err)).Inc()
+
+	return &AdmissionError{Err: err, RetryAfter: c.limits.MaxWait}
+}
+
+func newAdmissionController(limits AdmissionLimits) *admissionController {
+	if limits.MaxConcurrent == 0 {
+		return nil
+	}
+
+	return &admissionController{
+		limits: limits,
+		slots:  make(chan struct{}, limits.MaxConcurrent),
+		inFlight: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "snmpproxy",
+			Name:      "admission_in_flight",
+			Help:      "Number of the admitted SNMP operations that are being executed",
+		}),
+		queueDepth: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "snmpproxy",
+			Name:      "admission_queue_depth",
+			Help:      "Number of the SNMP operations waiting for admission",
+		}),
+		rejected: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "snmpproxy",
+			Name:      "admission_rejected_total",
+			Help:      "Number of the SNMP operations rejected by the admission control, by the reason",
+		}, []string{"reason"}),
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -17,11 +20,22 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	// DeadlineHeader may be used instead of the deadline field of the ApiRequest.
+	DeadlineHeader = "X-Request-Deadline"
+	// StreamContentType in the Accept header switches the response to a stream of newline-delimited JSON records:
+	// a StreamVarbind for every varbind as soon as it's received, and a StreamTrailer at the end.
+	StreamContentType = "application/x-ndjson"
+)
+
 type ApiListener struct {
 	validator         *RequestValidator
 	requester         Requester
 	logger            *zap.SugaredLogger
 	server            *http.Server
+	mux               *http.ServeMux
+	ctx               context.Context    // base of all the requests (and the jobs)
+	cancel            context.CancelFunc // aborts all the requests that are still being executed
 	socketPermissions os.FileMode
 }
 
@@ -68,9 +82,12 @@ func (l *ApiListener) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 
 	apiRequest := &ApiRequest{}
 	response := Response{}
+	streamed := false
 
 	defer func() {
-		_, _ = writer.Write(response.Bytes())
+		if !streamed {
+			_, _ = writer.Write(response.Bytes())
+		}
 	}()
 
 	body, err := io.ReadAll(request.Body)
@@ -92,6 +109,24 @@ func (l *ApiListener) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
+	if err = l.applyDeadlineHeader(request, apiRequest); err != nil {
+		l.logger.Debugw("invalid deadline header", zap.Error(err))
+		writer.WriteHeader(http.StatusBadRequest)
+
+		response.Error = err.Error()
+
+		return
+	}
+
+	if apiRequest.Cache, err = parseCacheControl(request.Header.Get("Cache-Control")); err != nil {
+		l.logger.Debugw("invalid cache control header", zap.Error(err))
+		writer.WriteHeader(http.StatusBadRequest)
+
+		response.Error = err.Error()
+
+		return
+	}
+
 	if err = l.validator.Validate(apiRequest); err != nil {
 		l.logger.Debugw("invalid API request", zap.Error(err), "request", apiRequest)
 		writer.WriteHeader(http.StatusBadRequest)
@@ -101,19 +136,202 @@ func (l *ApiListener) ServeHTTP(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
-	if result, err := l.requester.ExecuteRequest(apiRequest); err == nil {
+	if strings.Contains(request.Header.Get("Accept"), StreamContentType) {
+		streamed = true
+
+		l.streamRequest(writer, request, apiRequest)
+
+		return
+	}
+
+	result, err := l.requester.ExecuteRequest(request.Context(), apiRequest)
+	if result != nil && result.Cached != nil {
+		setCacheHeaders(writer, result.Cached)
+	}
+
+	if err == nil {
 		l.logger.Debugw("request successful", "request", apiRequest)
 
 		writer.WriteHeader(http.StatusOK)
 
-		response.Result = result
+		response.Result, response.Cursors = result.Varbinds, result.Cursors
+
+		return
+	}
+
+	if errors.Is(err, ErrDeadlineExceeded) {
+		l.logger.Debugw("request deadline exceeded", zap.Error(err), "request", apiRequest)
 	} else {
 		l.logger.Debugw("request failed", zap.Error(err), "request", apiRequest)
+	}
+
+	writeErrorHeader(writer, err)
+
+	response.Error = err.Error()
+	response.Code = ErrorCode(err)
 
+	// partial results (if allowed) or incomplete walks
+	if result != nil {
+		response.Result, response.Cursors = result.Varbinds, result.Cursors
+	}
+}
+
+// writeErrorHeader writes the status code of the failed request, and the Retry-After header of the rejected one.
+func writeErrorHeader(writer http.ResponseWriter, err error) {
+	var admissionErr *AdmissionError
+
+	switch {
+	case errors.Is(err, ErrDeadlineExceeded):
+		writer.WriteHeader(http.StatusGatewayTimeout)
+	case errors.As(err, &admissionErr):
+		writer.Header().Set("Retry-After", admissionErr.RetryAfterHeader())
+
+		if errors.Is(err, ErrQueueFull) {
+			writer.WriteHeader(http.StatusTooManyRequests)
+		} else {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	case errors.Is(err, ErrTargetUnavailable):
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	default:
 		writer.WriteHeader(http.StatusInternalServerError)
+	}
+}
 
-		response.Error = err.Error()
+// streamRequest writes the header of the stream once there is the first varbind (or once the request is done), so that
+// the request rejected by the admission control (see AdmissionLimits) before anything is streamed gets the same
+// response as if it wasn't streamed. Once the stream starts, the errors are only reported by its trailer.
+func (l *ApiListener) streamRequest(writer http.ResponseWriter, request *http.Request, apiRequest *ApiRequest) {
+	var (
+		mu      sync.Mutex
+		started bool
+	)
+
+	controller := http.NewResponseController(writer)
+	encoder := json.NewEncoder(writer)
+
+	start := func() {
+		if !started {
+			started = true
+
+			writer.Header().Set("Content-Type", StreamContentType)
+			writer.WriteHeader(http.StatusOK)
+		}
+	}
+
+	cursors, err := l.requester.StreamRequest(
+		request.Context(),
+		apiRequest,
+		func(requestNo int, oid string, value any) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			start()
+
+			if err := encoder.Encode(StreamVarbind{Request: requestNo, Oid: oid, Value: value}); err != nil {
+				return err
+			}
+
+			return controller.Flush()
+		},
+	)
+
+	var admissionErr *AdmissionError
+
+	// no more varbinds are coming at this point, so there is no need to lock
+	if !started && errors.As(err, &admissionErr) {
+		l.logger.Debugw("request rejected", zap.Error(err), "request", apiRequest)
+
+		writeErrorHeader(writer, err)
+
+		response := Response{Error: err.Error(), Code: ErrorCode(err)}
+		_, _ = writer.Write(response.Bytes())
+
+		return
+	}
+
+	start()
+
+	trailer := StreamTrailer{Status: StreamStatusOk, Cursors: cursors}
+
+	if err == nil {
+		l.logger.Debugw("request successful", "request", apiRequest)
+	} else {
+		l.logger.Debugw("request failed", zap.Error(err), "request", apiRequest)
+
+		trailer.Status = StreamStatusError
+		trailer.Error = err.Error()
+		trailer.Code = ErrorCode(err)
+	}
+
+	_ = encoder.Encode(trailer)
+}
+
+// setCacheHeaders tells whether the results were served from the cache (see CacheStatusHeader), and the age
+// of the oldest cached result in seconds.
+func setCacheHeaders(writer http.ResponseWriter, cached []time.Time) {
+	var (
+		oldest time.Time
+		hits   int
+	)
+
+	for _, stored := range cached {
+		if stored.IsZero() {
+			continue
+		}
+
+		if hits++; oldest.IsZero() || stored.Before(oldest) {
+			oldest = stored
+		}
 	}
+
+	switch hits {
+	case 0:
+		writer.Header().Set(CacheStatusHeader, CacheStatusMiss)
+
+		return
+	case len(cached):
+		writer.Header().Set(CacheStatusHeader, CacheStatusHit)
+	default:
+		writer.Header().Set(CacheStatusHeader, CacheStatusPartial)
+	}
+
+	writer.Header().Set("Age", strconv.Itoa(int(time.Since(oldest).Seconds())))
+}
+
+// applyDeadlineHeader sets the deadline of the request from the DeadlineHeader (seconds, or a duration string),
+// if it's present. If the deadline is set in both the body and the header, the shorter one is used.
+func (*ApiListener) applyDeadlineHeader(request *http.Request, apiRequest *ApiRequest) error {
+	header := request.Header.Get(DeadlineHeader)
+	if header == "" {
+		return nil
+	}
+
+	// the same as the deadline field, which is either a number or a string
+	data := json.RawMessage(header)
+	if !json.Valid(data) {
+		data, _ = json.Marshal(header)
+	}
+
+	deadline, err := unmarshalDuration(data)
+	if err != nil {
+		return fmt.Errorf("header %s %w", DeadlineHeader, err)
+	}
+
+	if deadline <= 0 {
+		return fmt.Errorf("header %s must be positive, got: %s", DeadlineHeader, header)
+	}
+
+	if apiRequest.Deadline == 0 || deadline < apiRequest.Deadline {
+		apiRequest.Deadline = deadline
+	}
+
+	return nil
+}
+
+// Handle registers an additional handler (e.g. an administrative endpoint) on the API listener.
+func (l *ApiListener) Handle(pattern string, handler http.Handler) {
+	l.mux.Handle(pattern, handler)
 }
 
 func (l *ApiListener) Close() {
@@ -121,6 +339,7 @@ func (l *ApiListener) Close() {
 	defer cancel()
 
 	if err := l.server.Shutdown(ctx); errors.Is(err, ctx.Err()) {
+		l.cancel()
 		l.server.Close()
 	}
 }
@@ -133,12 +352,23 @@ func NewApiListener(
 	socketPermissions os.FileMode,
 ) *ApiListener {
 	mux := http.NewServeMux()
+	ctx, cancel := context.WithCancel(context.Background())
 
 	listener := &ApiListener{
-		validator:         validator,
-		requester:         requester,
-		logger:            logger,
-		server:            &http.Server{Addr: listen, Handler: mux, ReadHeaderTimeout: time.Second},
+		validator: validator,
+		requester: requester,
+		logger:    logger,
+		server: &http.Server{
+			Addr:              listen,
+			Handler:           mux,
+			ReadHeaderTimeout: time.Second,
+			BaseContext: func(net.Listener) context.Context {
+				return ctx
+			},
+		},
+		mux:               mux,
+		ctx:               ctx,
+		cancel:            cancel,
 		socketPermissions: socketPermissions,
 	}
 
@@ -3,11 +3,13 @@ package snmpproxy_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -23,15 +25,46 @@ type mockRequester struct {
 	mock.Mock
 }
 
-func (r *mockRequester) ExecuteRequest(apiRequest *snmpproxy.ApiRequest) ([][]any, error) {
-	args := r.Mock.Called(apiRequest)
+func (r *mockRequester) ExecuteRequest(
+	ctx context.Context,
+	apiRequest *snmpproxy.ApiRequest,
+) (*snmpproxy.Result, error) {
+	args := r.Mock.Called(ctx, apiRequest)
 
 	result := args.Get(0)
 	if result == nil {
 		return nil, args.Error(1)
 	}
 
-	return result.([][]any), args.Error(1)
+	return result.(*snmpproxy.Result), args.Error(1)
+}
+
+func (r *mockRequester) ExecuteIndependently(
+	ctx context.Context,
+	apiRequest *snmpproxy.ApiRequest,
+) (*snmpproxy.Result, []error) {
+	args := r.Mock.Called(ctx, apiRequest)
+
+	return args.Get(0).(*snmpproxy.Result), args.Get(1).([]error)
+}
+
+func (*mockRequester) PartialResultPolicy() snmpproxy.PartialResultPolicy {
+	return snmpproxy.PartialResultsNone
+}
+
+func (r *mockRequester) StreamRequest(
+	ctx context.Context,
+	apiRequest *snmpproxy.ApiRequest,
+	fn snmpproxy.VarbindFunc,
+) ([]*snmpproxy.WalkCursor, error) {
+	args := r.Mock.Called(ctx, apiRequest, fn)
+
+	cursors := args.Get(0)
+	if cursors == nil {
+		return nil, args.Error(1)
+	}
+
+	return cursors.([]*snmpproxy.WalkCursor), args.Error(1)
 }
 
 type errReader struct{}
@@ -156,7 +189,7 @@ func TestListenerErrorRequestValidatorError(t *testing.T) {
 	response := recorder.Result()
 
 	assert.Equal(http.StatusBadRequest, response.StatusCode)
-	assert.Equal(`{"error":"maximum allowed timeout is 10 seconds, got 100 seconds"}`, read(response.Body))
+	assert.Equal(`{"error":"maximum allowed timeout is 10s, got 1m40s"}`, read(response.Body))
 }
 
 func TestListenerErrorRequesterError(t *testing.T) {
@@ -167,7 +200,7 @@ func TestListenerErrorRequesterError(t *testing.T) {
 	requester := &mockRequester{}
 	defer requester.AssertExpectations(t)
 
-	requester.On("ExecuteRequest", mock.Anything).Once().Return(nil, errors.New("some error"))
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(nil, errors.New("some error"))
 
 	listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
 
@@ -182,6 +215,212 @@ func TestListenerErrorRequesterError(t *testing.T) {
 	assert.Equal(`{"error":"some error"}`, read(response.Body))
 }
 
+func TestListenerErrorLimitExceeded(t *testing.T) {
+	assert := require.New(t)
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	err := fmt.Errorf("%w, walk returned more than 10 varbinds: .1.2.3", snmpproxy.ErrLimitExceeded)
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(nil, err)
+
+	listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+
+	request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(getRequestBody))
+
+	recorder := httptest.NewRecorder()
+	listener.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+
+	assert.Equal(http.StatusInternalServerError, response.StatusCode)
+	assert.Equal(
+		`{"error":"limit exceeded, walk returned more than 10 varbinds: .1.2.3","code":"limit_exceeded"}`,
+		read(response.Body),
+	)
+}
+
+func TestListenerWalkIncomplete(t *testing.T) {
+	assert := require.New(t)
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	result := &snmpproxy.Result{
+		Varbinds: [][]any{{".1.2.3.1", 123}},
+		Cursors:  []*snmpproxy.WalkCursor{{Root: ".1.2.3", Oid: ".1.2.3.1"}},
+	}
+	err := fmt.Errorf("%w: .1.2.3 (%w, stopped after .1.2.3.1)", snmpproxy.ErrTimeout, snmpproxy.ErrWalkIncomplete)
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(result, err)
+
+	listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+
+	request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(getRequestBody))
+
+	recorder := httptest.NewRecorder()
+	listener.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+
+	assert.Equal(http.StatusInternalServerError, response.StatusCode)
+	assert.Equal(
+		`{"error":"timeout: .1.2.3 (walk incomplete, stopped after .1.2.3.1)","code":"walk_incomplete",`+
+			`"result":[[".1.2.3.1",123]],"cursors":["LjEuMi4zLC4xLjIuMy4x"]}`,
+		read(response.Body),
+	)
+}
+
+func TestListenerAdmission(t *testing.T) {
+	tests := []struct {
+		name               string
+		err                error
+		stream             bool // rejected before anything is streamed
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "queue full",
+			err:                snmpproxy.ErrQueueFull,
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedBody:       `{"error":"too many requests are waiting for admission","code":"queue_full"}`,
+		},
+		{
+			name:               "admission timeout",
+			err:                snmpproxy.ErrAdmissionTimeout,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody:       `{"error":"request wasn't admitted in time","code":"admission_timeout"}`,
+		},
+		{
+			name:               "queue full of stream",
+			err:                snmpproxy.ErrQueueFull,
+			stream:             true,
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedBody:       `{"error":"too many requests are waiting for admission","code":"queue_full"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+			requester := &mockRequester{}
+			defer requester.AssertExpectations(t)
+
+			admissionErr := &snmpproxy.AdmissionError{Err: test.err, RetryAfter: time.Millisecond * 1500}
+			if test.stream {
+				requester.On("StreamRequest", mock.Anything, mock.Anything, mock.Anything).Once().Return(nil, admissionErr)
+			} else {
+				requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(nil, admissionErr)
+			}
+
+			listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+
+			request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(getRequestBody))
+			if test.stream {
+				request.Header.Set("Accept", snmpproxy.StreamContentType)
+			}
+
+			recorder := httptest.NewRecorder()
+			listener.ServeHTTP(recorder, request)
+
+			response := recorder.Result()
+			assert.Equal(test.expectedStatusCode, response.StatusCode)
+			assert.Equal("2", response.Header.Get("Retry-After"))
+			assert.Equal(test.expectedBody, read(response.Body))
+		})
+	}
+}
+
+func TestListenerCache(t *testing.T) {
+	maxAge := time.Second * 10
+
+	tests := []struct {
+		name           string
+		header         string
+		cached         []time.Time
+		expectedCache  snmpproxy.CacheControl
+		expectedStatus string
+		expectedAge    string
+	}{
+		{name: "not cached"},
+		{
+			name:           "miss",
+			header:         "no-cache",
+			cached:         []time.Time{{}},
+			expectedCache:  snmpproxy.CacheControl{NoCache: true},
+			expectedStatus: "MISS",
+		},
+		{
+			name:           "partial",
+			header:         "max-age=10",
+			cached:         []time.Time{{}, time.Now().Add(-time.Second * 3)},
+			expectedCache:  snmpproxy.CacheControl{MaxAge: &maxAge},
+			expectedStatus: "PARTIAL",
+			expectedAge:    "3",
+		},
+		{
+			name:           "hit",
+			header:         "no-transform, Max-Age=10",
+			cached:         []time.Time{time.Now().Add(-time.Second * 5), time.Now().Add(-time.Second * 2)},
+			expectedCache:  snmpproxy.CacheControl{MaxAge: &maxAge},
+			expectedStatus: "HIT",
+			expectedAge:    "5",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+			requester := &mockRequester{}
+			defer requester.AssertExpectations(t)
+
+			requester.On("ExecuteRequest", mock.Anything, mock.MatchedBy(func(apiRequest *snmpproxy.ApiRequest) bool {
+				return reflect.DeepEqual(test.expectedCache, apiRequest.Cache)
+			})).Once().Return(&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}}, Cached: test.cached}, nil)
+
+			listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+
+			request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(getRequestBody))
+			request.Header.Set("Cache-Control", test.header)
+
+			recorder := httptest.NewRecorder()
+			listener.ServeHTTP(recorder, request)
+
+			response := recorder.Result()
+
+			assert.Equal(http.StatusOK, response.StatusCode)
+			assert.Equal(test.expectedStatus, response.Header.Get(snmpproxy.CacheStatusHeader))
+			assert.Equal(test.expectedAge, response.Header.Get("Age"))
+		})
+	}
+}
+
+func TestListenerInvalidCacheControl(t *testing.T) {
+	assert := require.New(t)
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	listener := snmpproxy.NewApiListener(newValidator(), &mockRequester{}, zap.NewNop().Sugar(), "", 0)
+
+	request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(getRequestBody))
+	request.Header.Set("Cache-Control", "max-age=soon")
+
+	recorder := httptest.NewRecorder()
+	listener.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+
+	assert.Equal(http.StatusBadRequest, response.StatusCode)
+	assert.Equal(`{"error":"invalid max-age of the Cache-Control header, got: soon"}`, read(response.Body))
+}
+
 func TestListenerNoError(t *testing.T) {
 	assert := require.New(t)
 
@@ -190,7 +429,10 @@ func TestListenerNoError(t *testing.T) {
 	requester := &mockRequester{}
 	defer requester.AssertExpectations(t)
 
-	requester.On("ExecuteRequest", mock.Anything).Once().Return([][]any{{".1.2.3", 123}}, nil)
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}}},
+		nil,
+	)
 
 	listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
 
@@ -205,6 +447,184 @@ func TestListenerNoError(t *testing.T) {
 	assert.Equal(`{"result":[[".1.2.3",123]]}`, read(response.Body))
 }
 
+func TestListenerDeadlineExceeded(t *testing.T) {
+	assert := require.New(t)
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}, nil}},
+		fmt.Errorf("%w (5s)", snmpproxy.ErrDeadlineExceeded),
+	)
+
+	listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+
+	request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(getRequestBody))
+
+	recorder := httptest.NewRecorder()
+	listener.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+
+	assert.Equal(http.StatusGatewayTimeout, response.StatusCode)
+	assert.Equal(
+		`{"error":"request deadline exceeded (5s)","code":"deadline_exceeded","result":[[".1.2.3",123],null]}`,
+		read(response.Body),
+	)
+}
+
+func TestListenerDeadlineHeader(t *testing.T) {
+	tests := []struct {
+		name             string
+		header           string
+		bodyDeadline     string
+		expectedDeadline time.Duration
+		err              string
+	}{
+		{name: "header only", header: "5", expectedDeadline: 5 * time.Second},
+		{name: "shorter header", header: "5", bodyDeadline: "10", expectedDeadline: 5 * time.Second},
+		{name: "shorter body", header: "10", bodyDeadline: "5", expectedDeadline: 5 * time.Second},
+		{name: "fractional header", header: "1.5", expectedDeadline: 1500 * time.Millisecond},
+		{name: "duration header", header: "500ms", bodyDeadline: `"1s"`, expectedDeadline: 500 * time.Millisecond},
+		{
+			name:   "invalid header",
+			header: "soon",
+			err: `{"error":"header X-Request-Deadline must be a number of seconds or a duration string ` +
+				`(e.g. \"250ms\"), got \"soon\""}`,
+		},
+		{
+			name:   "zero header",
+			header: "0",
+			err:    `{"error":"header X-Request-Deadline must be positive, got: 0"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+			requester := &mockRequester{}
+			defer requester.AssertExpectations(t)
+
+			if test.err == "" {
+				requester.On(
+					"ExecuteRequest",
+					mock.Anything,
+					mock.MatchedBy(func(apiRequest *snmpproxy.ApiRequest) bool {
+						return apiRequest.Deadline == test.expectedDeadline
+					}),
+				).Once().Return(&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}}}, nil)
+			}
+
+			listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+
+			body := getRequestBody
+			if test.bodyDeadline != "" {
+				body = strings.Replace(body, `"timeout": 3,`, `"timeout": 3, "deadline": `+test.bodyDeadline+",", 1)
+			}
+
+			request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(body))
+			request.Header.Set(snmpproxy.DeadlineHeader, test.header)
+
+			recorder := httptest.NewRecorder()
+			listener.ServeHTTP(recorder, request)
+
+			response := recorder.Result()
+
+			if test.err == "" {
+				assert.Equal(http.StatusOK, response.StatusCode)
+			} else {
+				assert.Equal(http.StatusBadRequest, response.StatusCode)
+				assert.Equal(test.err, read(response.Body))
+			}
+		})
+	}
+}
+
+func TestListenerStream(t *testing.T) {
+	tests := []struct {
+		name            string
+		cursors         []*snmpproxy.WalkCursor
+		err             error
+		expectedTrailer string
+	}{
+		{name: "success", expectedTrailer: `{"status":"ok"}`},
+		{
+			name:            "success with cursor",
+			cursors:         []*snmpproxy.WalkCursor{{Root: ".1.2.3", Oid: ".1.2.3.1"}, nil},
+			expectedTrailer: `{"status":"ok","cursors":["LjEuMi4zLC4xLjIuMy4x",null]}`,
+		},
+		{name: "failure", err: errors.New("some error"), expectedTrailer: `{"status":"error","error":"some error"}`},
+		{
+			// the stream started already, so the rejection is only reported by the trailer
+			name:            "rejected once streaming",
+			err:             &snmpproxy.AdmissionError{Err: snmpproxy.ErrQueueFull},
+			expectedTrailer: `{"status":"error","error":"too many requests are waiting for admission","code":"queue_full"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+			requester := &mockRequester{}
+			defer requester.AssertExpectations(t)
+
+			requester.On("StreamRequest", mock.Anything, mock.Anything, mock.Anything).Once().Run(
+				func(args mock.Arguments) {
+					fn := args.Get(2).(snmpproxy.VarbindFunc)
+					assert.NoError(fn(0, ".1.2.3.1", 123))
+					assert.NoError(fn(1, ".4.5.6", "lorem"))
+				},
+			).Return(test.cursors, test.err)
+
+			listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+
+			request := httptest.NewRequest("POST", "/snmp-proxy", strings.NewReader(getRequestBody))
+			request.Header.Set("Accept", snmpproxy.StreamContentType)
+
+			recorder := httptest.NewRecorder()
+			listener.ServeHTTP(recorder, request)
+
+			response := recorder.Result()
+			assert.Equal(http.StatusOK, response.StatusCode)
+			assert.Equal(snmpproxy.StreamContentType, response.Header.Get("Content-Type"))
+			assert.True(recorder.Flushed)
+			assert.Equal(
+				`{"request":0,"oid":".1.2.3.1","value":123}`+"\n"+
+					`{"request":1,"oid":".4.5.6","value":"lorem"}`+"\n"+
+					test.expectedTrailer+"\n",
+				read(response.Body),
+			)
+		})
+	}
+}
+
+func TestListenerHandle(t *testing.T) {
+	assert := require.New(t)
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	listener := snmpproxy.NewApiListener(newValidator(), &mockRequester{}, zap.NewNop().Sugar(), "localhost:15722", 0)
+	listener.Handle("/admin/test", http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusTeapot)
+	}))
+	assert.NoError(listener.Start())
+
+	defer listener.Close()
+
+	time.Sleep(time.Millisecond * 10)
+
+	response, err := http.Get("http://localhost:15722/admin/test")
+	assert.NoError(err)
+	assert.Equal(http.StatusTeapot, response.StatusCode)
+}
+
 func TestStartAndClose(t *testing.T) {
 	assert := require.New(t)
 
@@ -213,7 +633,10 @@ func TestStartAndClose(t *testing.T) {
 	requester := &mockRequester{}
 	defer requester.AssertExpectations(t)
 
-	requester.On("ExecuteRequest", mock.Anything).Once().Return([][]any{{".1.2.3", 123}}, nil)
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}}},
+		nil,
+	)
 
 	listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "localhost:15721", 0)
 	listener.Start()
@@ -240,7 +663,10 @@ func TestStartAndCloseOnSocket(t *testing.T) {
 	requester := &mockRequester{}
 	defer requester.AssertExpectations(t)
 
-	requester.On("ExecuteRequest", mock.Anything).Once().Return([][]any{{".1.2.3", 123}}, nil)
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}}},
+		nil,
+	)
 
 	f, err := os.CreateTemp("", "snmp-proxy-test-*.sock")
 	assert.NoError(err)
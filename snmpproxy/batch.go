@@ -0,0 +1,290 @@
+package snmpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+// BatchLimits limit the batch API requests, see BatchRequest.
+type BatchLimits struct {
+	MaxTargets     uint // zero means unlimited
+	MaxConcurrency uint // targets executed at once per batch request (at least one), also the default concurrency
+}
+
+// BatchRequest executes the same requests for many targets at once. Its fields are those of the ApiRequest, shared
+// by all the targets, and the targets, each of which sets the host and may override any of the shared fields
+// (e.g. the community or the profile).
+type BatchRequest struct {
+	ApiRequests []*ApiRequest // one per target
+	Concurrency uint          // optional, targets executed at once, see BatchLimits.MaxConcurrency
+}
+
+func (r *BatchRequest) UnmarshalJSON(data []byte) error {
+	var (
+		shared map[string]json.RawMessage
+		t      struct {
+			Targets     []map[string]json.RawMessage `json:"targets"`
+			Concurrency uint                         `json:"concurrency"`
+		}
+	)
+
+	if err := errors.Join(json.Unmarshal(data, &shared), json.Unmarshal(data, &t)); err != nil {
+		return fmt.Errorf("failed to unmarshal request body into BatchRequest struct, got %+v: %w", string(data), err)
+	}
+
+	if len(t.Targets) == 0 {
+		return errors.New("field targets mustn't be empty")
+	}
+
+	delete(shared, "targets")
+	delete(shared, "concurrency")
+
+	r.ApiRequests = make([]*ApiRequest, len(t.Targets))
+	r.Concurrency = t.Concurrency
+
+	for targetNo, target := range t.Targets {
+		fields := maps.Clone(shared)
+		if fields == nil {
+			fields = make(map[string]json.RawMessage, len(target))
+		}
+
+		// the target may override the shared timeout by either of its fields
+		if _, ok := target["timeout"]; ok {
+			delete(fields, "timeout_ms")
+		}
+
+		if _, ok := target["timeout_ms"]; ok {
+			delete(fields, "timeout")
+		}
+
+		maps.Copy(fields, target)
+
+		apiRequestData, err := json.Marshal(fields)
+		if err != nil {
+			panic(err)
+		}
+
+		r.ApiRequests[targetNo] = &ApiRequest{}
+		if err = r.ApiRequests[targetNo].UnmarshalJSON(apiRequestData); err != nil {
+			return fmt.Errorf("target %d: %w", targetNo, err)
+		}
+	}
+
+	return nil
+}
+
+// BatchResult is the result of the requests for a single target of the BatchRequest. The streamed batch response
+// (see StreamContentType) consists of a BatchResult for every target as soon as it's done, and a StreamTrailer.
+type BatchResult struct {
+	Target  int           `json:"target"` // index of the target in the BatchRequest
+	Host    string        `json:"host"`
+	Error   string        `json:"error,omitempty"`
+	Code    string        `json:"code,omitempty"` // see ErrorCode
+	Result  [][]any       `json:"result,omitempty"`
+	Cursors []*WalkCursor `json:"cursors,omitempty"`
+}
+
+// BatchResponse holds the results of all the targets of the BatchRequest, in the order of the targets.
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// batchHandler serves the batch API requests. Every target is executed as a separate API request (its SNMP operations
+// are admitted like any others, see AdmissionLimits), and its failure doesn't affect the other targets.
+type batchHandler struct {
+	listener *ApiListener
+	limits   BatchLimits
+}
+
+func (h *batchHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	batchRequest, err := h.parse(request)
+	if err != nil {
+		h.listener.logger.Debugw("invalid batch API request", zap.Error(err))
+		writer.WriteHeader(http.StatusBadRequest)
+
+		response := Response{Error: err.Error()}
+		_, _ = writer.Write(response.Bytes())
+
+		return
+	}
+
+	concurrency := h.limits.MaxConcurrency
+	if batchRequest.Concurrency != 0 {
+		concurrency = batchRequest.Concurrency
+	}
+
+	results := h.execute(request.Context(), batchRequest, concurrency)
+
+	if strings.Contains(request.Header.Get("Accept"), StreamContentType) {
+		h.stream(writer, results)
+
+		return
+	}
+
+	response := BatchResponse{Results: make([]BatchResult, len(batchRequest.ApiRequests))}
+	for result := range results {
+		response.Results[result.Target] = result
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		panic(err)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(body)
+}
+
+// parse reads, unmarshals and validates the batch API request.
+func (h *batchHandler) parse(request *http.Request) (*BatchRequest, error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	batchRequest := &BatchRequest{}
+	if err = json.Unmarshal(body, batchRequest); err != nil {
+		return nil, err
+	}
+
+	if h.limits.MaxTargets != 0 && uint(len(batchRequest.ApiRequests)) > h.limits.MaxTargets {
+		return nil, fmt.Errorf(
+			"maximum allowed number of targets is %d, got %d",
+			h.limits.MaxTargets,
+			len(batchRequest.ApiRequests),
+		)
+	}
+
+	if batchRequest.Concurrency > h.limits.MaxConcurrency {
+		return nil, fmt.Errorf(
+			"maximum allowed concurrency is %d, got %d",
+			h.limits.MaxConcurrency,
+			batchRequest.Concurrency,
+		)
+	}
+
+	cacheControl, err := parseCacheControl(request.Header.Get("Cache-Control"))
+	if err != nil {
+		return nil, err
+	}
+
+	for targetNo, apiRequest := range batchRequest.ApiRequests {
+		apiRequest.Cache = cacheControl
+
+		if err = h.listener.applyDeadlineHeader(request, apiRequest); err != nil {
+			return nil, err
+		}
+
+		if err = h.listener.validator.Validate(apiRequest); err != nil {
+			return nil, fmt.Errorf("target %d: %w", targetNo, err)
+		}
+	}
+
+	return batchRequest, nil
+}
+
+// execute executes the targets, at most concurrency of them at once. The returned channel receives their results
+// as they are done, and it's closed once all of them are. It must be drained.
+func (h *batchHandler) execute(ctx context.Context, batchRequest *BatchRequest, concurrency uint) <-chan BatchResult {
+	results := make(chan BatchResult)
+	slots := make(chan struct{}, concurrency)
+
+	go func() {
+		var wg sync.WaitGroup
+
+		for targetNo, apiRequest := range batchRequest.ApiRequests {
+			slots <- struct{}{}
+
+			wg.Add(1)
+
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+
+				results <- h.executeTarget(ctx, targetNo, apiRequest)
+			}()
+		}
+
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+func (h *batchHandler) executeTarget(ctx context.Context, targetNo int, apiRequest *ApiRequest) BatchResult {
+	batchResult := BatchResult{Target: targetNo, Host: apiRequest.Host}
+
+	result, err := h.listener.requester.ExecuteRequest(ctx, apiRequest)
+
+	// partial results or incomplete walks, if the request failed
+	if result != nil {
+		batchResult.Result, batchResult.Cursors = result.Varbinds, result.Cursors
+	}
+
+	if err != nil {
+		h.listener.logger.Debugw("batch target failed", zap.Error(err), "request", apiRequest)
+
+		batchResult.Error = err.Error()
+		batchResult.Code = ErrorCode(err)
+	} else {
+		h.listener.logger.Debugw("batch target successful", "request", apiRequest)
+	}
+
+	return batchResult
+}
+
+func (*batchHandler) stream(writer http.ResponseWriter, results <-chan BatchResult) {
+	controller := http.NewResponseController(writer)
+	encoder := json.NewEncoder(writer)
+
+	writer.Header().Set("Content-Type", StreamContentType)
+	writer.WriteHeader(http.StatusOK)
+
+	for result := range results {
+		// the client may be gone, but the channel must be drained anyway (the targets are cancelled by then)
+		if err := encoder.Encode(result); err == nil {
+			_ = controller.Flush()
+		}
+	}
+
+	_ = encoder.Encode(StreamTrailer{Status: StreamStatusOk})
+}
+
+// BatchHandler returns the handler of the batch API requests (see BatchRequest), to be registered by Handle.
+// It registers the metrics of the batch requests, so it may only be called once.
+func (l *ApiListener) BatchHandler(limits BatchLimits) http.Handler {
+	limits.MaxConcurrency = max(limits.MaxConcurrency, 1)
+
+	metricOpts := prometheus.HistogramOpts{
+		Namespace: "snmpproxy",
+		Name:      "batch_requests",
+		Help:      "Number of issued batch requests and their duration",
+	}
+
+	return promhttp.InstrumentHandlerDuration(
+		promauto.NewHistogramVec(metricOpts, nil),
+		&batchHandler{listener: l, limits: limits},
+	)
+}
@@ -0,0 +1,186 @@
+package snmpproxy_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const batchRequestBody = `
+{
+    "version": "2c",
+    "timeout": 3,
+    "targets": [
+        {"host": "host1"},
+        {"host": "host2", "community": "secret", "timeout_ms": 500},
+        {"host": "host3", "version": "1"}
+    ],
+    "requests": [
+        {"request_type": "get", "oids": [".1.2.3"]}
+    ]
+}
+`
+
+func TestBatchRequestUnmarshal(t *testing.T) {
+	assert := require.New(t)
+
+	var batchRequest snmpproxy.BatchRequest
+	assert.NoError(batchRequest.UnmarshalJSON([]byte(batchRequestBody)))
+
+	requests := []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}}
+
+	assert.Equal(
+		[]*snmpproxy.ApiRequest{
+			{Host: "host1", Community: "public", Version: 1, Timeout: 3 * time.Second, Requests: requests},
+			{Host: "host2", Community: "secret", Version: 1, Timeout: 500 * time.Millisecond, Requests: requests},
+			{Host: "host3", Community: "public", Version: 0, Timeout: 3 * time.Second, Requests: requests},
+		},
+		batchRequest.ApiRequests,
+	)
+}
+
+func TestBatchHandler(t *testing.T) {
+	results := []string{
+		`{"target":0,"host":"host1","result":[[".1.2.3",1]]}`,
+		`{"target":1,"host":"host2","error":"timeout","code":"timeout"}`,
+		`{"target":2,"host":"host3","result":[[".1.2.3",3]]}`,
+	}
+
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream %t", stream), func(t *testing.T) {
+			assert := require.New(t)
+
+			prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+			var (
+				inFlight     atomic.Int32
+				exceeded     atomic.Bool // more targets were executed at once than allowed
+				host3Started = make(chan struct{})
+			)
+
+			host := func(host string) any {
+				return mock.MatchedBy(func(apiRequest *snmpproxy.ApiRequest) bool { return apiRequest.Host == host })
+			}
+			execute := func(wait <-chan struct{}) func(mock.Arguments) {
+				return func(mock.Arguments) {
+					if inFlight.Add(1) > 2 {
+						exceeded.Store(true)
+					}
+
+					if wait != nil {
+						<-wait
+					}
+
+					inFlight.Add(-1)
+				}
+			}
+
+			requester := &mockRequester{}
+			defer requester.AssertExpectations(t)
+
+			// the third target is only executed once the second one is done, so the first one is done last
+			requester.On("ExecuteRequest", mock.Anything, host("host1")).Once().Run(execute(host3Started)).
+				Return(&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 1}}}, nil)
+			requester.On("ExecuteRequest", mock.Anything, host("host2")).Once().Run(execute(nil)).
+				Return(nil, snmpproxy.ErrTimeout)
+			requester.On("ExecuteRequest", mock.Anything, host("host3")).Once().Run(func(args mock.Arguments) {
+				close(host3Started)
+				execute(nil)(args)
+			}).Return(&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 3}}}, nil)
+
+			listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+			handler := listener.BatchHandler(snmpproxy.BatchLimits{MaxTargets: 3, MaxConcurrency: 2})
+
+			request := httptest.NewRequest("POST", "/snmp-proxy/batch", strings.NewReader(batchRequestBody))
+			if stream {
+				request.Header.Set("Accept", snmpproxy.StreamContentType)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			response := recorder.Result()
+			assert.Equal(http.StatusOK, response.StatusCode)
+			assert.False(exceeded.Load())
+
+			if !stream {
+				assert.Equal(`{"results":[`+strings.Join(results, ",")+`]}`, read(response.Body))
+
+				return
+			}
+
+			assert.Equal(snmpproxy.StreamContentType, response.Header.Get("Content-Type"))
+
+			records := strings.Split(strings.TrimSuffix(read(response.Body), "\n"), "\n")
+			assert.Len(records, 4)
+			assert.Equal(results[1], records[0])
+			assert.ElementsMatch([]string{results[0], results[2]}, records[1:3])
+			assert.Equal(`{"status":"ok"}`, records[3])
+		})
+	}
+}
+
+func TestBatchHandlerInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestBody   string
+		expectedError string
+	}{
+		{
+			name:          "no targets",
+			requestBody:   `{"version": "2c", "timeout": 1, "targets": [], "requests": []}`,
+			expectedError: "field targets mustn't be empty",
+		},
+		{
+			name:          "invalid target",
+			requestBody:   `{"version": "2c", "timeout": 1, "targets": [{"host": "host1"}, {}], "requests": []}`,
+			expectedError: "target 1: field host mustn't be empty",
+		},
+		{
+			name: "too many targets",
+			requestBody: `{"version": "2c", "timeout": 1, "targets": [{"host": "1"}, {"host": "2"}, {"host": "3"}],` +
+				` "requests": []}`,
+			expectedError: "maximum allowed number of targets is 2, got 3",
+		},
+		{
+			name:          "concurrency too high",
+			requestBody:   `{"version": "2c", "timeout": 1, "targets": [{"host": "1"}], "concurrency": 5, "requests": []}`,
+			expectedError: "maximum allowed concurrency is 4, got 5",
+		},
+		{
+			name: "target not valid",
+			requestBody: `{"version": "2c", "timeout": 1, "targets": [{"host": "1"}, {"host": "2", "timeout": 100}],` +
+				` "requests": [{"request_type": "get", "oids": [".1.2.3"]}]}`,
+			expectedError: "target 1: maximum allowed timeout is 10s, got 1m40s",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+			listener := snmpproxy.NewApiListener(newValidator(), &mockRequester{}, zap.NewNop().Sugar(), "", 0)
+			handler := listener.BatchHandler(snmpproxy.BatchLimits{MaxTargets: 2, MaxConcurrency: 4})
+
+			request := httptest.NewRequest("POST", "/snmp-proxy/batch", strings.NewReader(test.requestBody))
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			response := recorder.Result()
+			assert.Equal(http.StatusBadRequest, response.StatusCode)
+			assert.Contains(read(response.Body), test.expectedError)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package snmpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// CircuitBreakerSettings configure the CircuitBreaker.
+type CircuitBreakerSettings struct {
+	MaxTimeouts uint          // consecutive timeouts that open the circuit, zero disables the circuit breaker
+	CoolDown    time.Duration // how long the requests fail fast before a probe is let through
+}
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitStatus is the state of the circuit of a target.
+type CircuitStatus struct {
+	State     string     `json:"state"`
+	Timeouts  uint       `json:"timeouts"` // consecutive timeouts of the target
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+}
+
+// requestOutcome is what the SNMP request says about the availability of the target.
+type requestOutcome int
+
+const (
+	outcomeUnknown = requestOutcome(iota) // e.g. the request was cancelled
+	outcomeResponse
+	outcomeTimeout
+)
+
+type circuit struct {
+	timeouts  uint
+	openUntil time.Time
+	lastSeen  time.Time // of the last timeout, the stale circuits are pruned, see CircuitBreaker.prune
+	probing   bool      // the half-open circuit lets through only a single request at once
+}
+
+// CircuitBreaker fails the requests to the unresponsive targets fast, so that they don't waste the time of the clients
+// (timeout * (retries + 1) per request). After CircuitBreakerSettings.MaxTimeouts consecutive timeouts, the circuit
+// of the target opens and the requests fail with ErrTargetUnavailable. Once the cool-down passes, the circuit is
+// half-open: a single probe request is sent, and if it gets a response, the circuit is closed again. Otherwise, it's
+// open for another cool-down. The targets without any timeouts for a cool-down (or for another one once their circuit
+// is half-open) are forgotten. The breaker also serves the states of the circuits as JSON over HTTP.
+type CircuitBreaker struct {
+	settings CircuitBreakerSettings
+	mu       sync.Mutex
+	circuits map[string]*circuit // only the targets with timeouts are kept
+	pruned   time.Time
+	open     prometheus.Gauge
+	opened   prometheus.Counter
+	rejected prometheus.Counter
+}
+
+// allow returns ErrTargetUnavailable if the circuit of the target is open, otherwise the function that must be called
+// with the outcome of the request.
+func (b *CircuitBreaker) allow(target string) (func(requestOutcome), error) {
+	if b == nil {
+		return func(requestOutcome) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var probe bool
+
+	if c, ok := b.circuits[target]; ok && c.timeouts >= b.settings.MaxTimeouts {
+		if c.probing || time.Now().Before(c.openUntil) {
+			b.rejected.Inc()
+
+			return nil, fmt.Errorf("%w: %s (after %d consecutive timeouts)", ErrTargetUnavailable, target, c.timeouts)
+		}
+
+		c.probing, probe = true, true
+	}
+
+	return func(outcome requestOutcome) { b.done(target, probe, outcome) }, nil
+}
+
+func (b *CircuitBreaker) done(target string, probe bool, outcome requestOutcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	// the circuits of all the targets are checked at most once per cool-down
+	if now.Sub(b.pruned) >= b.settings.CoolDown {
+		b.prune(now)
+	}
+
+	c, ok := b.circuits[target]
+
+	switch {
+	case outcome == outcomeResponse:
+		if ok && c.timeouts >= b.settings.MaxTimeouts {
+			b.open.Dec()
+		}
+
+		delete(b.circuits, target)
+
+		return
+	case outcome == outcomeTimeout:
+		if !ok {
+			c = &circuit{}
+			b.circuits[target] = c
+		}
+
+		if c.timeouts++; c.timeouts == b.settings.MaxTimeouts {
+			b.opened.Inc()
+			b.open.Inc()
+		}
+
+		c.lastSeen = now
+
+		if c.timeouts >= b.settings.MaxTimeouts {
+			c.openUntil = now.Add(b.settings.CoolDown)
+		}
+	case !ok:
+		return
+	}
+
+	if probe {
+		c.probing = false
+	}
+}
+
+// prune forgets the circuits of the targets that had no timeouts for a cool-down, or for another one once their
+// circuit is half-open (nobody has sent the probe). The circuits that are being probed are kept.
+func (b *CircuitBreaker) prune(now time.Time) {
+	b.pruned = now
+
+	for target, c := range b.circuits {
+		open := c.timeouts >= b.settings.MaxTimeouts
+
+		expires := c.lastSeen.Add(b.settings.CoolDown)
+		if open {
+			expires = c.openUntil.Add(b.settings.CoolDown)
+		}
+
+		if c.probing || now.Before(expires) {
+			continue
+		}
+
+		if open {
+			b.open.Dec()
+		}
+
+		delete(b.circuits, target)
+	}
+}
+
+// All returns the states of the circuits of all the targets that had any timeouts recently.
+func (b *CircuitBreaker) All() map[string]CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.prune(now)
+
+	statuses := make(map[string]CircuitStatus, len(b.circuits))
+
+	for target, c := range b.circuits {
+		status := CircuitStatus{State: CircuitClosed, Timeouts: c.timeouts}
+
+		if c.timeouts >= b.settings.MaxTimeouts {
+			status.State = CircuitHalfOpen
+
+			if now.Before(c.openUntil) {
+				openUntil := c.openUntil
+				status.State, status.OpenUntil = CircuitOpen, &openUntil
+			}
+		}
+
+		statuses[target] = status
+	}
+
+	return statuses
+}
+
+func (b *CircuitBreaker) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	body, err := json.Marshal(b.All())
+	if err != nil {
+		panic(err)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(body)
+}
+
+func newCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	return &CircuitBreaker{
+		settings: settings,
+		circuits: make(map[string]*circuit),
+		open: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "snmpproxy",
+			Name:      "circuits_open",
+			Help:      "Number of the targets with open (or half-open) circuits",
+		}),
+		opened: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "snmpproxy",
+			Name:      "circuits_opened_total",
+			Help:      "Number of times the circuit of a target was opened",
+		}),
+		rejected: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "snmpproxy",
+			Name:      "circuit_rejected_requests_total",
+			Help:      "Number of the SNMP requests that failed fast because the circuit of their target was open",
+		}),
+	}
+}
@@ -0,0 +1,295 @@
+package snmpproxy
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CacheStatusHeader of the response tells whether the results were served from the cache: CacheStatusHit if all
+	// of them were, CacheStatusPartial if some of them were, and CacheStatusMiss if none of them were.
+	CacheStatusHeader = "X-Cache"
+	// cacheCleanupInterval is how often the expired entries are removed from the cache.
+	cacheCleanupInterval = time.Minute
+	// defaultCacheMaxEntries is used when the maximum isn't set, see CachingRequester.SetMaxEntries.
+	defaultCacheMaxEntries = 100000
+)
+
+const (
+	CacheStatusHit     = "HIT"
+	CacheStatusPartial = "PARTIAL"
+	CacheStatusMiss    = "MISS"
+)
+
+// CacheTtl sets for how long the results of the requests for the OIDs with the prefix are cached. If there are
+// multiple matching prefixes, the longest one wins. The results of the requests for multiple OIDs are cached
+// for the shortest TTL of them.
+type CacheTtl struct {
+	Prefix string
+	Ttl    time.Duration
+}
+
+// CacheControl is set by the Cache-Control header of the API request.
+type CacheControl struct {
+	NoCache bool           // the cached results mustn't be used, the fresh ones are still cached
+	MaxAge  *time.Duration // optional, only the cached results that aren't older are used
+}
+
+// parseCacheControl parses the directives no-cache and max-age (in seconds) of the Cache-Control header,
+// other directives are ignored.
+func parseCacheControl(header string) (CacheControl, error) {
+	var cacheControl CacheControl
+
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		switch strings.ToLower(name) {
+		case "no-cache":
+			cacheControl.NoCache = true
+		case "max-age":
+			seconds, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return CacheControl{}, fmt.Errorf("invalid max-age of the Cache-Control header, got: %s", value)
+			}
+
+			maxAge := time.Duration(seconds) * time.Second
+			cacheControl.MaxAge = &maxAge
+		}
+	}
+
+	return cacheControl, nil
+}
+
+type cacheEntry struct {
+	key     string
+	result  []any
+	cursor  *WalkCursor
+	stored  time.Time
+	expires time.Time
+}
+
+// CachingRequester caches the results of the requests of another Requester, so that the devices polled by many
+// clients for the same OIDs get the requests only once per TTL (see CacheTtl). The results are cached per request
+// of the ApiRequest, keyed by the target, the credentials, the type and the OIDs of the request. The requests that
+// fail aren't cached, and neither are the streamed ones. Once the cache is full, the least recently used entries
+// are evicted.
+type CachingRequester struct {
+	requester   Requester
+	ttls        []CacheTtl
+	maxEntries  int
+	mu          sync.Mutex
+	entries     map[string]*list.Element // of the recent list
+	recent      *list.List               // of the *cacheEntry, the most recently used first
+	lastCleanup time.Time
+}
+
+func (r *CachingRequester) ExecuteRequest(ctx context.Context, apiRequest *ApiRequest) (*Result, error) {
+	now := time.Now()
+	count := len(apiRequest.Requests)
+	result := &Result{Varbinds: make([][]any, count), Cached: make([]time.Time, count)}
+	cursors := make([]*WalkCursor, count)
+	keys := make([]string, count)
+	ttls := make([]time.Duration, count)
+
+	var (
+		missing    []int // numbers of the requests that have to be executed, in the order of the subRequest
+		subRequest = *apiRequest
+	)
+
+	subRequest.Requests = nil
+
+	r.mu.Lock()
+
+	for requestNo, request := range apiRequest.Requests {
+		keys[requestNo], ttls[requestNo] = r.key(apiRequest, request), r.ttl(request)
+
+		element, ok := r.entries[keys[requestNo]]
+		if ok && ttls[requestNo] != 0 && r.usable(apiRequest.Cache, element.Value.(*cacheEntry), now) {
+			entry := element.Value.(*cacheEntry)
+			result.Varbinds[requestNo], cursors[requestNo] = entry.result, entry.cursor
+			result.Cached[requestNo] = entry.stored
+
+			r.recent.MoveToFront(element)
+
+			continue
+		}
+
+		missing = append(missing, requestNo)
+		subRequest.Requests = append(subRequest.Requests, request)
+	}
+
+	r.mu.Unlock()
+
+	var err error
+
+	if len(missing) != 0 {
+		var subResult *Result
+
+		subResult, err = r.requester.ExecuteRequest(ctx, &subRequest)
+		if subResult == nil {
+			return nil, err
+		}
+
+		r.mu.Lock()
+
+		for i, requestNo := range missing {
+			result.Varbinds[requestNo] = subResult.Varbinds[i]
+			if subResult.Cursors != nil {
+				cursors[requestNo] = subResult.Cursors[i]
+			}
+
+			if err == nil && ttls[requestNo] != 0 {
+				r.store(&cacheEntry{
+					key:     keys[requestNo],
+					result:  subResult.Varbinds[i],
+					cursor:  cursors[requestNo],
+					stored:  now,
+					expires: now.Add(ttls[requestNo]),
+				})
+			}
+		}
+
+		r.removeExpired(now)
+
+		r.mu.Unlock()
+	}
+
+	for _, cursor := range cursors {
+		if cursor != nil {
+			result.Cursors = cursors
+
+			break
+		}
+	}
+
+	return result, err
+}
+
+func (r *CachingRequester) StreamRequest(
+	ctx context.Context,
+	apiRequest *ApiRequest,
+	fn VarbindFunc,
+) ([]*WalkCursor, error) {
+	return r.requester.StreamRequest(ctx, apiRequest, fn)
+}
+
+// SetMaxEntries sets the maximum number of the cached results, see defaultCacheMaxEntries.
+func (r *CachingRequester) SetMaxEntries(maxEntries uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxEntries = int(maxEntries)
+	if r.maxEntries == 0 {
+		r.maxEntries = defaultCacheMaxEntries
+	}
+
+	r.evict()
+}
+
+// usable returns whether the entry may be used by the request with the given CacheControl.
+func (*CachingRequester) usable(cacheControl CacheControl, entry *cacheEntry, now time.Time) bool {
+	if cacheControl.NoCache || now.After(entry.expires) {
+		return false
+	}
+
+	return cacheControl.MaxAge == nil || now.Sub(entry.stored) <= *cacheControl.MaxAge
+}
+
+func (*CachingRequester) key(apiRequest *ApiRequest, request Request) string {
+	key, err := json.Marshal([]any{
+		apiRequest.Host,
+		apiRequest.Community,
+		apiRequest.Version,
+		apiRequest.Profile,
+		request.RequestType,
+		request.Oids,
+		request.MaxResults,
+		request.Cursor,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return string(key)
+}
+
+// ttl returns for how long the result of the request may be cached, zero if it mustn't be cached at all.
+func (r *CachingRequester) ttl(request Request) time.Duration {
+	var ttl time.Duration
+
+	for i, oid := range request.Oids {
+		var (
+			oidTtl    time.Duration
+			prefixLen = -1
+		)
+
+		for _, cacheTtl := range r.ttls {
+			prefix := strings.TrimSuffix(cacheTtl.Prefix, ".")
+			if len(prefix) > prefixLen && (oid == prefix || strings.HasPrefix(oid, prefix+".")) {
+				oidTtl, prefixLen = cacheTtl.Ttl, len(prefix)
+			}
+		}
+
+		if i == 0 || oidTtl < ttl {
+			ttl = oidTtl
+		}
+	}
+
+	return ttl
+}
+
+// store adds the entry as the most recently used one, replacing the previous entry of the key, if there is any.
+func (r *CachingRequester) store(entry *cacheEntry) {
+	if element, ok := r.entries[entry.key]; ok {
+		element.Value = entry
+		r.recent.MoveToFront(element)
+
+		return
+	}
+
+	r.entries[entry.key] = r.recent.PushFront(entry)
+
+	r.evict()
+}
+
+// evict removes the least recently used entries above the maximum.
+func (r *CachingRequester) evict() {
+	for r.recent.Len() > r.maxEntries {
+		r.remove(r.recent.Back())
+	}
+}
+
+func (r *CachingRequester) remove(element *list.Element) {
+	r.recent.Remove(element)
+	delete(r.entries, element.Value.(*cacheEntry).key)
+}
+
+func (r *CachingRequester) removeExpired(now time.Time) {
+	if now.Sub(r.lastCleanup) < cacheCleanupInterval {
+		return
+	}
+
+	r.lastCleanup = now
+
+	for _, element := range r.entries {
+		if now.After(element.Value.(*cacheEntry).expires) {
+			r.remove(element)
+		}
+	}
+}
+
+func NewCachingRequester(requester Requester, ttls []CacheTtl) *CachingRequester {
+	return &CachingRequester{
+		requester:  requester,
+		ttls:       ttls,
+		maxEntries: defaultCacheMaxEntries,
+		entries:    make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+}
@@ -0,0 +1,152 @@
+package snmpproxy_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachingRequester(t *testing.T) {
+	assert := require.New(t)
+
+	maxAge := time.Duration(0)
+
+	sysDescr := snmpproxy.Request{RequestType: snmpproxy.Get, Oids: []string{".1.3.6.1.2.1.1.1.0"}}
+	sysUpTime := snmpproxy.Request{RequestType: snmpproxy.Get, Oids: []string{".1.3.6.1.2.1.1.3.0"}}
+	ifTable := snmpproxy.Request{RequestType: snmpproxy.Walk, Oids: []string{".1.3.6.1.2.1.2.2"}, MaxResults: 1}
+
+	apiRequest := func(cacheControl snmpproxy.CacheControl, requests ...snmpproxy.Request) *snmpproxy.ApiRequest {
+		return &snmpproxy.ApiRequest{Host: "localhost", Community: "public", Requests: requests, Cache: cacheControl}
+	}
+
+	expectRequests := func(requests ...snmpproxy.Request) any {
+		return mock.MatchedBy(func(apiRequest *snmpproxy.ApiRequest) bool {
+			return reflect.DeepEqual(requests, apiRequest.Requests)
+		})
+	}
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	cursor := &snmpproxy.WalkCursor{Root: ".1.3.6.1.2.1.2.2", Oid: ".1.3.6.1.2.1.2.2.1.1.1"}
+
+	requester.On("ExecuteRequest", mock.Anything, expectRequests(sysDescr, sysUpTime, ifTable)).Once().Return(
+		&snmpproxy.Result{
+			Varbinds: [][]any{{".1.3.6.1.2.1.1.1.0", "a"}, {".1.3.6.1.2.1.1.3.0", 1}, {".1.3.6.1.2.1.2.2.1.1.1", 1}},
+			Cursors:  []*snmpproxy.WalkCursor{nil, nil, cursor},
+		},
+		nil,
+	)
+	requester.On("ExecuteRequest", mock.Anything, expectRequests(sysUpTime)).Once().Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.3.6.1.2.1.1.3.0", 2}}},
+		nil,
+	)
+	requester.On("ExecuteRequest", mock.Anything, expectRequests(sysDescr, sysUpTime)).Once().Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.3.6.1.2.1.1.1.0", "b"}, {".1.3.6.1.2.1.1.3.0", 3}}},
+		nil,
+	)
+	requester.On("ExecuteRequest", mock.Anything, expectRequests(sysUpTime, ifTable)).Once().Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.3.6.1.2.1.1.3.0", 4}, nil}},
+		errors.New("some error"),
+	)
+
+	cachingRequester := snmpproxy.NewCachingRequester(requester, []snmpproxy.CacheTtl{
+		{Prefix: ".", Ttl: time.Minute},
+		{Prefix: ".1.3.6.1.2.1.1.3", Ttl: 0}, // sysUpTime changes all the time
+	})
+
+	// nothing is cached yet
+	result, err := cachingRequester.ExecuteRequest(
+		context.Background(),
+		apiRequest(snmpproxy.CacheControl{}, sysDescr, sysUpTime, ifTable),
+	)
+	assert.NoError(err)
+	assert.Equal(
+		[][]any{{".1.3.6.1.2.1.1.1.0", "a"}, {".1.3.6.1.2.1.1.3.0", 1}, {".1.3.6.1.2.1.2.2.1.1.1", 1}},
+		result.Varbinds,
+	)
+	assert.Equal([]*snmpproxy.WalkCursor{nil, nil, cursor}, result.Cursors)
+	assert.Equal([]time.Time{{}, {}, {}}, result.Cached)
+
+	// only sysUpTime isn't cached
+	result, err = cachingRequester.ExecuteRequest(
+		context.Background(),
+		apiRequest(snmpproxy.CacheControl{}, sysDescr, sysUpTime, ifTable),
+	)
+	assert.NoError(err)
+	assert.Equal(
+		[][]any{{".1.3.6.1.2.1.1.1.0", "a"}, {".1.3.6.1.2.1.1.3.0", 2}, {".1.3.6.1.2.1.2.2.1.1.1", 1}},
+		result.Varbinds,
+	)
+	assert.Equal([]*snmpproxy.WalkCursor{nil, nil, cursor}, result.Cursors)
+	assert.False(result.Cached[0].IsZero())
+	assert.True(result.Cached[1].IsZero())
+	assert.False(result.Cached[2].IsZero())
+
+	// the cache is bypassed, but the fresh result is cached
+	result, err = cachingRequester.ExecuteRequest(
+		context.Background(),
+		apiRequest(snmpproxy.CacheControl{NoCache: true}, sysDescr, sysUpTime),
+	)
+	assert.NoError(err)
+	assert.Equal([][]any{{".1.3.6.1.2.1.1.1.0", "b"}, {".1.3.6.1.2.1.1.3.0", 3}}, result.Varbinds)
+	assert.Nil(result.Cursors)
+
+	result, err = cachingRequester.ExecuteRequest(context.Background(), apiRequest(snmpproxy.CacheControl{}, sysDescr))
+	assert.NoError(err)
+	assert.Equal([][]any{{".1.3.6.1.2.1.1.1.0", "b"}}, result.Varbinds)
+
+	// the cached walk is too old, and the failed request isn't cached
+	time.Sleep(time.Millisecond)
+
+	result, err = cachingRequester.ExecuteRequest(
+		context.Background(),
+		apiRequest(snmpproxy.CacheControl{MaxAge: &maxAge}, sysUpTime, ifTable),
+	)
+	assert.EqualError(err, "some error")
+	assert.Equal([][]any{{".1.3.6.1.2.1.1.3.0", 4}, nil}, result.Varbinds)
+	assert.Nil(result.Cursors)
+
+	result, err = cachingRequester.ExecuteRequest(context.Background(), apiRequest(snmpproxy.CacheControl{}, ifTable))
+	assert.NoError(err)
+	assert.Equal([][]any{{".1.3.6.1.2.1.2.2.1.1.1", 1}}, result.Varbinds)
+	assert.Equal([]*snmpproxy.WalkCursor{cursor}, result.Cursors)
+}
+
+func TestCachingRequesterMaxEntries(t *testing.T) {
+	assert := require.New(t)
+
+	get := func(oid string) *snmpproxy.ApiRequest {
+		return &snmpproxy.ApiRequest{
+			Host:      "localhost",
+			Community: "public",
+			Requests:  []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{oid}}},
+		}
+	}
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	// the evicted result is requested again
+	for oid, times := range map[string]int{".1.2.1": 1, ".1.2.2": 2, ".1.2.3": 1} {
+		requester.On("ExecuteRequest", mock.Anything, get(oid)).Times(times).Return(
+			&snmpproxy.Result{Varbinds: [][]any{{oid, 1}}},
+			nil,
+		)
+	}
+
+	cachingRequester := snmpproxy.NewCachingRequester(requester, []snmpproxy.CacheTtl{{Prefix: ".", Ttl: time.Minute}})
+	cachingRequester.SetMaxEntries(2)
+
+	for _, oid := range []string{".1.2.1", ".1.2.2", ".1.2.1", ".1.2.3", ".1.2.1", ".1.2.2"} {
+		result, err := cachingRequester.ExecuteRequest(context.Background(), get(oid))
+		assert.NoError(err)
+		assert.Equal([][]any{{oid, 1}}, result.Varbinds)
+	}
+}
@@ -0,0 +1,114 @@
+package snmpproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+var errCallbackNotAllowed = errors.New("callback destination isn't allowed")
+
+// callbackPolicy decides where the finished jobs may be delivered, see JobSettings.CallbackAllowlist.
+type callbackPolicy struct {
+	hosts    map[string]bool // trusted, whatever their addresses are
+	networks []netip.Prefix
+}
+
+func newCallbackPolicy(allowlist []string) (callbackPolicy, error) {
+	var policy callbackPolicy
+
+	for _, entry := range allowlist {
+		if !strings.Contains(entry, "/") {
+			if policy.hosts == nil {
+				policy.hosts = make(map[string]bool)
+			}
+
+			policy.hosts[strings.ToLower(entry)] = true
+
+			continue
+		}
+
+		network, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return callbackPolicy{}, fmt.Errorf("invalid network of the callback allowlist: %w", err)
+		}
+
+		policy.networks = append(policy.networks, network.Masked())
+	}
+
+	return policy, nil
+}
+
+func (p callbackPolicy) allowsHost(host string) bool {
+	return p.hosts[strings.ToLower(host)]
+}
+
+// allowsAddr checks the address the callback is about to be delivered to. Only the public addresses are allowed
+// if there is no allowlist.
+func (p callbackPolicy) allowsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if p.hosts == nil && p.networks == nil {
+		return addr.IsGlobalUnicast() && !addr.IsPrivate()
+	}
+
+	for _, network := range p.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowsUrl rejects the callback URLs that can't be allowed whatever their hosts resolve to. The resolved addresses
+// are checked once the callback is delivered.
+func (p callbackPolicy) allowsUrl(callbackUrl *url.URL) bool {
+	host := callbackUrl.Hostname()
+	if p.allowsHost(host) {
+		return true
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.allowsAddr(addr)
+	}
+
+	return p.hosts == nil || p.networks != nil
+}
+
+// client returns the HTTP client that only connects to the allowed destinations, including those of the redirects.
+func (p callbackPolicy) client() *http.Client {
+	trusted := &net.Dialer{}
+	checked := &net.Dialer{
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !p.allowsAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errCallbackNotAllowed, addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				if host, _, err := net.SplitHostPort(address); err == nil && p.allowsHost(host) {
+					return trusted.DialContext(ctx, network, address)
+				}
+
+				return checked.DialContext(ctx, network, address)
+			},
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package snmpproxy
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// errNotCoalescable is returned by getCoalesced when the response can't be attributed to the single requests.
+var errNotCoalescable = errors.New("requests can't be coalesced")
+
+// executeGets executes the get (or getNext) requests of the ApiRequest together: their OIDs are coalesced into
+// as few PDUs as the agent allows, and the varbinds are then split back per request. If the agent responds
+// with an error status, which can't be attributed to a single request, the requests are executed one by one.
+func (r *GosnmpRequester) executeGets(ctx context.Context, execution *execution, requestNos []int) {
+	apiRequest := execution.apiRequest
+	results := make([]requestResult, len(requestNos))
+
+	defer func() {
+		for _, result := range results {
+			execution.results <- result
+		}
+	}()
+
+	snmp, err := r.createSnmpHandler(ctx, apiRequest)
+	if err != nil {
+		for i, requestNo := range requestNos {
+			results[i] = requestResult{
+				requestNo: requestNo,
+				error:     r.maybeConvertErrToTimeout(ctx, err, apiRequest.Requests[requestNo].Oids),
+			}
+		}
+
+		return
+	}
+
+	defer snmp.Close()
+
+	if len(requestNos) == 1 {
+		results[0] = r.executeGet(ctx, snmp, execution, requestNos[0])
+
+		return
+	}
+
+	var oids []string
+	for _, requestNo := range requestNos {
+		oids = append(oids, apiRequest.Requests[requestNo].Oids...)
+	}
+
+	variables, err := snmp.getCoalesced(apiRequest.Requests[requestNos[0]].RequestType, oids)
+
+	for i, requestNo := range requestNos {
+		request := apiRequest.Requests[requestNo]
+
+		switch {
+		case errors.Is(err, errNotCoalescable):
+			results[i] = r.executeGet(ctx, snmp, execution, requestNo)
+		case err != nil:
+			results[i] = requestResult{requestNo: requestNo, error: r.maybeConvertErrToTimeout(ctx, err, request.Oids)}
+		default:
+			results[i] = r.processGetResult(execution, requestNo, &gosnmp.SnmpPacket{
+				Error:     gosnmp.NoError,
+				Variables: variables[:len(request.Oids)],
+			})
+
+			variables = variables[len(request.Oids):]
+		}
+	}
+}
+
+// getCoalesced gets the varbinds of all the OIDs, using as few PDUs as possible: it starts with the maximum
+// number of OIDs per PDU, and lowers it if the agent says the response would be too big.
+func (h *snmpHandler) getCoalesced(requestType RequestType, oids []string) ([]gosnmp.SnmpPDU, error) {
+	getter := h.getter(requestType)
+	variables := make([]gosnmp.SnmpPDU, 0, len(oids))
+	size := h.MaxOids
+
+	for len(oids) != 0 {
+		pdu := oids[:min(len(oids), size)]
+
+		packet, err := getter(pdu)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case packet.Error == gosnmp.TooBig && len(pdu) > 1:
+			size = len(pdu) / 2
+
+			continue
+		case packet.Error != gosnmp.NoError || len(packet.Variables) != len(pdu):
+			return nil, errNotCoalescable
+		}
+
+		variables = append(variables, packet.Variables...)
+		oids = oids[len(pdu):]
+	}
+
+	return variables, nil
+}
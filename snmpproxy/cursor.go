@@ -0,0 +1,52 @@
+package snmpproxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// WalkCursor allows to continue a walk that was stopped early. For the clients, it's an opaque string.
+type WalkCursor struct {
+	Root string // OID of the walk request
+	Oid  string // last OID that was returned to the client
+}
+
+func (c *WalkCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Root + "," + c.Oid))
+}
+
+func (c *WalkCursor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *WalkCursor) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("cursor must be a string, got %s: %w", string(data), err)
+	}
+
+	cursor, err := ParseWalkCursor(s)
+	if err != nil {
+		return err
+	}
+
+	*c = *cursor
+
+	return nil
+}
+
+func ParseWalkCursor(s string) (*WalkCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor \"%s\"", s)
+	}
+
+	root, oid, ok := strings.Cut(string(decoded), ",")
+	if !ok || !strings.HasPrefix(root, ".") || !strings.HasPrefix(oid, root+".") {
+		return nil, fmt.Errorf("invalid cursor \"%s\"", s)
+	}
+
+	return &WalkCursor{Root: root, Oid: oid}, nil
+}
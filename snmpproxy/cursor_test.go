@@ -0,0 +1,48 @@
+package snmpproxy_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkCursor(t *testing.T) {
+	assert := require.New(t)
+
+	cursor := &snmpproxy.WalkCursor{Root: ".1.3.6.1.2.1.17.4.3", Oid: ".1.3.6.1.2.1.17.4.3.1.1.0.1.2.3.4.5"}
+
+	b, err := json.Marshal([]*snmpproxy.WalkCursor{nil, cursor})
+	assert.NoError(err)
+	assert.Equal(`[null,"`+cursor.String()+`"]`, string(b))
+
+	var cursors []*snmpproxy.WalkCursor
+
+	assert.NoError(json.Unmarshal(b, &cursors))
+	assert.Equal([]*snmpproxy.WalkCursor{nil, cursor}, cursors)
+}
+
+func TestParseWalkCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		err    string
+	}{
+		{name: "not base64", cursor: "!!!", err: `invalid cursor "!!!"`},
+		{name: "missing separator", cursor: "LjEuMg", err: `invalid cursor "LjEuMg"`},               // .1.2
+		{name: "OID outside of root", cursor: "LjEuMiwuMS4z", err: `invalid cursor "LjEuMiwuMS4z"`}, // .1.2,.1.3
+		{name: "OID equal to root", cursor: "LjEuMiwuMS4y", err: `invalid cursor "LjEuMiwuMS4y"`},   // .1.2,.1.2
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor, err := snmpproxy.ParseWalkCursor(test.cursor)
+			require.Nil(t, cursor)
+			require.EqualError(t, err, test.err)
+		})
+	}
+
+	cursor, err := snmpproxy.ParseWalkCursor("LjEuMiwuMS4yLjM") // .1.2,.1.2.3
+	require.NoError(t, err)
+	require.Equal(t, &snmpproxy.WalkCursor{Root: ".1.2", Oid: ".1.2.3"}, cursor)
+}
@@ -58,15 +58,25 @@ func (v *SnmpVersion) UnmarshalJSON(data []byte) error {
 }
 
 type Request struct {
-	RequestType    RequestType `json:"request_type"`
-	Oids           []string    `json:"oids"`
-	MaxRepetitions uint32      `json:"max_repetitions"`
+	RequestType    RequestType  `json:"request_type"`
+	Oids           []string     `json:"oids"`
+	MaxRepetitions uint32       `json:"max_repetitions"`
+	MaxResults     uint32       `json:"max_results"`     // walk only, returns a cursor if there are more results
+	Cursor         *WalkCursor  `json:"cursor"`          // walk only, continues the previous walk
+	PartialResults bool         `json:"partial_results"` // walk only, keeps the varbinds received before a timeout
+	WalkStrategy   WalkStrategy `json:"walk_strategy"`   // walk only, overrides the configured WalkStrategy
+	Parallel       uint8        `json:"parallel"`        // walk only, walks the columns with this many connections
+	// AutoMaxRepetitions is set by max_repetitions "auto", the max repetitions are then learned for the host.
+	AutoMaxRepetitions bool `json:"-"`
 }
 
 func (r *Request) UnmarshalJSON(data []byte) error {
 	type tmp Request
 
-	var t tmp
+	var t struct {
+		tmp
+		MaxRepetitions json.RawMessage `json:"max_repetitions"`
+	}
 
 	if err := json.Unmarshal(data, &t); err != nil {
 		return fmt.Errorf("failed to unmarshal Request struct, got %+v: %w", string(data), err)
@@ -76,7 +86,15 @@ func (r *Request) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("field request_type mustn't be empty")
 	}
 
-	*r = Request(t)
+	if string(t.MaxRepetitions) == `"auto"` {
+		t.AutoMaxRepetitions = true
+	} else if t.MaxRepetitions != nil {
+		if err := json.Unmarshal(t.MaxRepetitions, &t.tmp.MaxRepetitions); err != nil {
+			return fmt.Errorf("field max_repetitions must be a number or \"auto\", got %s", string(t.MaxRepetitions))
+		}
+	}
+
+	*r = Request(t.tmp)
 
 	return nil
 }
@@ -87,13 +105,24 @@ type ApiRequest struct {
 	Version   SnmpVersion   `json:"version"`
 	Retries   uint8         `json:"retries"`
 	Timeout   time.Duration `json:"timeout"`
+	Deadline  time.Duration `json:"deadline"` // optional, caps the total duration of all the requests
+	Profile   string        `json:"profile"`
 	Requests  []Request     `json:"requests"`
+	// RetryPolicy optionally overrides the retry policy of the device profile, see RetryPolicies.
+	RetryPolicy *RetryPolicy `json:"retry_policy"`
+	Cache       CacheControl `json:"-"` // set by the Cache-Control header, see CachingRequester
 }
 
 func (r *ApiRequest) UnmarshalJSON(data []byte) error {
 	type tmp ApiRequest
 
-	var t tmp
+	var t struct {
+		tmp
+		Timeout    json.RawMessage `json:"timeout"`     // seconds, or a duration string
+		TimeoutMs  uint64          `json:"timeout_ms"`  // may be used instead of the timeout
+		Deadline   json.RawMessage `json:"deadline"`    // seconds, or a duration string
+		DeadlineMs uint64          `json:"deadline_ms"` // may be used instead of the deadline
+	}
 
 	if err := json.Unmarshal(data, &t); err != nil {
 		return fmt.Errorf("failed to unmarshal request body into ApiRequest struct, got %+v: %w", string(data), err)
@@ -119,20 +148,69 @@ func (r *ApiRequest) UnmarshalJSON(data []byte) error {
 		}
 	}
 
-	if t.Timeout == 0 {
+	switch {
+	case t.Timeout != nil && t.TimeoutMs != 0:
+		return fmt.Errorf("fields timeout and timeout_ms mustn't be used together")
+	case t.TimeoutMs != 0:
+		t.tmp.Timeout = time.Duration(t.TimeoutMs) * time.Millisecond
+	case t.Timeout != nil:
+		timeout, err := unmarshalDuration(t.Timeout)
+		if err != nil {
+			return fmt.Errorf("field timeout %w", err)
+		}
+
+		t.tmp.Timeout = timeout
+	}
+
+	if t.tmp.Timeout <= 0 {
 		return fmt.Errorf("field timeout mustn't be empty or zero")
 	}
 
-	t.Timeout *= time.Second
+	switch {
+	case t.Deadline != nil && t.DeadlineMs != 0:
+		return fmt.Errorf("fields deadline and deadline_ms mustn't be used together")
+	case t.DeadlineMs != 0:
+		t.tmp.Deadline = time.Duration(t.DeadlineMs) * time.Millisecond
+	case t.Deadline != nil:
+		deadline, err := unmarshalDuration(t.Deadline)
+		if err != nil {
+			return fmt.Errorf("field deadline %w", err)
+		}
 
-	*r = ApiRequest(t)
+		t.tmp.Deadline = deadline
+	}
+
+	if t.tmp.Deadline < 0 {
+		return fmt.Errorf("field deadline mustn't be negative")
+	}
+
+	*r = ApiRequest(t.tmp)
 
 	return nil
 }
 
+// unmarshalDuration unmarshals a number of seconds (which may be fractional) or a duration string, e.g. "250ms".
+func unmarshalDuration(data json.RawMessage) (time.Duration, error) {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if duration, err := time.ParseDuration(s); err == nil {
+			return duration, nil
+		}
+	}
+
+	return 0, fmt.Errorf("must be a number of seconds or a duration string (e.g. \"250ms\"), got %s", string(data))
+}
+
 type Response struct {
-	Error  string  `json:"error,omitempty"`
-	Result [][]any `json:"result,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Code    string        `json:"code,omitempty"` // see ErrorCode
+	Result  [][]any       `json:"result,omitempty"`
+	Cursors []*WalkCursor `json:"cursors,omitempty"`
 }
 
 func (r *Response) Bytes() []byte {
@@ -143,3 +221,23 @@ func (r *Response) Bytes() []byte {
 
 	return b
 }
+
+// StreamVarbind is a single record of the streamed response, see StreamContentType.
+type StreamVarbind struct {
+	Request int    `json:"request"` // index of the request in the ApiRequest
+	Oid     string `json:"oid"`
+	Value   any    `json:"value"`
+}
+
+const (
+	StreamStatusOk    = "ok"
+	StreamStatusError = "error"
+)
+
+// StreamTrailer is the last record of the streamed response.
+type StreamTrailer struct {
+	Status  string        `json:"status"`
+	Error   string        `json:"error,omitempty"`
+	Code    string        `json:"code,omitempty"` // see ErrorCode
+	Cursors []*WalkCursor `json:"cursors,omitempty"`
+}
@@ -96,13 +96,23 @@ func TestUnmarshalRequest(t *testing.T) {
 {
     "request_type": "walk",
     "oids": [".1.2.3"],
-    "max_repetitions": 10
+    "max_repetitions": 10,
+    "max_results": 100,
+    "cursor": "LjEuMi4zLC4xLjIuMy40",
+    "partial_results": true,
+    "walk_strategy": "auto",
+    "parallel": 4
 }
 `,
 			expected: snmpproxy.Request{
 				RequestType:    snmpproxy.Walk,
 				Oids:           []string{".1.2.3"},
 				MaxRepetitions: 10,
+				MaxResults:     100,
+				Cursor:         &snmpproxy.WalkCursor{Root: ".1.2.3", Oid: ".1.2.3.4"},
+				PartialResults: true,
+				WalkStrategy:   snmpproxy.WalkStrategyAuto,
+				Parallel:       4,
 			},
 			err: "",
 		},
@@ -120,6 +130,27 @@ func TestUnmarshalRequest(t *testing.T) {
 			},
 			err: "",
 		},
+		{
+			name: "auto max repetitions",
+			raw: `
+{
+    "request_type": "walk",
+    "oids": [".1.2.3"],
+    "max_repetitions": "auto"
+}
+`,
+			expected: snmpproxy.Request{
+				RequestType:        snmpproxy.Walk,
+				Oids:               []string{".1.2.3"},
+				AutoMaxRepetitions: true,
+			},
+		},
+		{
+			name:     "invalid max repetitions",
+			raw:      `{"request_type": "walk", "oids": [".1.2.3"], "max_repetitions": "many"}`,
+			expected: snmpproxy.Request{},
+			err:      `field max_repetitions must be a number or "auto", got "many"`,
+		},
 		{
 			name:     "missing request type",
 			raw:      "{}",
@@ -163,10 +194,13 @@ func TestUnmarshalApiRequest(t *testing.T) {
     "community": "public",
     "version": "2c",
     "timeout": 10,
+    "deadline": 30,
     "retries": 3,
+    "profile": "vendor",
     "requests": [
         {"request_type": "walk", "oids": [".1.2.3"], "max_repetitions": 10}
-    ]
+    ],
+    "retry_policy": {"backoff": "exponential", "max_timeout": "20s", "jitter": 0.1}
 }
 `,
 			expected: snmpproxy.ApiRequest{
@@ -174,10 +208,17 @@ func TestUnmarshalApiRequest(t *testing.T) {
 				Community: "public",
 				Version:   snmpproxy.SnmpVersion(gosnmp.Version2c),
 				Timeout:   10 * time.Second,
+				Deadline:  30 * time.Second,
 				Retries:   3,
+				Profile:   "vendor",
 				Requests: []snmpproxy.Request{
 					{RequestType: snmpproxy.Walk, Oids: []string{".1.2.3"}, MaxRepetitions: 10},
 				},
+				RetryPolicy: &snmpproxy.RetryPolicy{
+					Backoff:    snmpproxy.BackoffExponential,
+					MaxTimeout: 20 * time.Second,
+					Jitter:     0.1,
+				},
 			},
 			err: "",
 		},
@@ -258,6 +299,165 @@ func TestUnmarshalApiRequest(t *testing.T) {
 			expected: snmpproxy.ApiRequest{},
 			err:      "field timeout mustn't be empty or zero",
 		},
+		{
+			name: "negative deadline",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": 3,
+    "deadline": -1
+}
+`,
+			expected: snmpproxy.ApiRequest{},
+			err:      "field deadline mustn't be negative",
+		},
+		{
+			name: "fractional timeout",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": 0.25,
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			expected: snmpproxy.ApiRequest{
+				Host:      "localhost",
+				Community: "public",
+				Version:   snmpproxy.SnmpVersion(gosnmp.Version2c),
+				Timeout:   250 * time.Millisecond,
+				Requests:  []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+		},
+		{
+			name: "timeout duration string",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": "1.5s",
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			expected: snmpproxy.ApiRequest{
+				Host:      "localhost",
+				Community: "public",
+				Version:   snmpproxy.SnmpVersion(gosnmp.Version2c),
+				Timeout:   1500 * time.Millisecond,
+				Requests:  []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+		},
+		{
+			name: "timeout in milliseconds",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout_ms": 50,
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			expected: snmpproxy.ApiRequest{
+				Host:      "localhost",
+				Community: "public",
+				Version:   snmpproxy.SnmpVersion(gosnmp.Version2c),
+				Timeout:   50 * time.Millisecond,
+				Requests:  []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+		},
+		{
+			name: "both timeout and timeout_ms",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": 1,
+    "timeout_ms": 50,
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			err: `fields timeout and timeout_ms mustn't be used together`,
+		},
+		{
+			name: "invalid timeout",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": "soon",
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			err: `field timeout must be a number of seconds or a duration string (e.g. "250ms"), got "soon"`,
+		},
+		{
+			name: "deadline duration string",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": 1,
+    "deadline": "1.5s",
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			expected: snmpproxy.ApiRequest{
+				Host:      "localhost",
+				Community: "public",
+				Version:   snmpproxy.SnmpVersion(gosnmp.Version2c),
+				Timeout:   time.Second,
+				Deadline:  1500 * time.Millisecond,
+				Requests:  []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+		},
+		{
+			name: "deadline in milliseconds",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": 1,
+    "deadline_ms": 500,
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			expected: snmpproxy.ApiRequest{
+				Host:      "localhost",
+				Community: "public",
+				Version:   snmpproxy.SnmpVersion(gosnmp.Version2c),
+				Timeout:   time.Second,
+				Deadline:  500 * time.Millisecond,
+				Requests:  []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+		},
+		{
+			name: "both deadline and deadline_ms",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": 1,
+    "deadline": 1,
+    "deadline_ms": 500,
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			err: `fields deadline and deadline_ms mustn't be used together`,
+		},
+		{
+			name: "invalid deadline",
+			raw: `
+{
+    "host": "localhost",
+    "version": "2c",
+    "timeout": 1,
+    "deadline": "soon",
+    "requests": [{"request_type": "get", "oids": [".1.2.3"]}]
+}
+`,
+			err: `field deadline must be a number of seconds or a duration string (e.g. "250ms"), got "soon"`,
+		},
 		{
 			name:     "invalid json",
 			raw:      "{",
@@ -291,6 +491,14 @@ func TestMarshalResponse(t *testing.T) {
 			response: snmpproxy.Response{Result: [][]any{{".1.2.3", 123, ".4.5.6", "lorem"}}},
 			expected: `{"result":[[".1.2.3",123,".4.5.6","lorem"]]}`,
 		},
+		{
+			name: "with cursors",
+			response: snmpproxy.Response{
+				Result:  [][]any{{".1.2.3", 123}, {".4.5.6.7", "lorem"}},
+				Cursors: []*snmpproxy.WalkCursor{nil, {Root: ".4.5.6", Oid: ".4.5.6.7"}},
+			},
+			expected: `{"result":[[".1.2.3",123],[".4.5.6.7","lorem"]],"cursors":[null,"LjQuNS42LC40LjUuNi43"]}`,
+		},
 		{
 			name:     "failure",
 			response: snmpproxy.Response{Error: "some error"},
@@ -0,0 +1,59 @@
+package snmpproxy
+
+import "errors"
+
+var (
+	ErrTimeout        = errors.New("timeout")
+	ErrWalkIncomplete = errors.New("walk incomplete")
+	// ErrDeadlineExceeded is returned if the deadline of the ApiRequest expires, see PartialResultPolicy.
+	ErrDeadlineExceeded = errors.New("request deadline exceeded")
+	ErrLimitExceeded    = errors.New("limit exceeded")
+	ErrOidNotIncreasing = errors.New("OID not increasing")
+	ErrQueueFull        = errors.New("too many requests are waiting for admission")
+	ErrAdmissionTimeout = errors.New("request wasn't admitted in time")
+	// ErrTargetUnavailable is returned without sending the request if the circuit of the target is open,
+	// see CircuitBreaker.
+	ErrTargetUnavailable = errors.New("target unavailable")
+	// ErrTooManyJobs is returned if the client has too many jobs running already, see JobSettings.
+	ErrTooManyJobs = errors.New("too many jobs of the client are running")
+)
+
+// Error codes allow the clients to tell apart the errors that need special handling, see ErrorCode.
+const (
+	ErrorCodeWalkIncomplete    = "walk_incomplete"
+	ErrorCodeDeadlineExceeded  = "deadline_exceeded"
+	ErrorCodeTimeout           = "timeout"
+	ErrorCodeLimitExceeded     = "limit_exceeded"
+	ErrorCodeOidNotIncreasing  = "oid_not_increasing"
+	ErrorCodeQueueFull         = "queue_full"
+	ErrorCodeAdmissionTimeout  = "admission_timeout"
+	ErrorCodeTargetUnavailable = "target_unavailable"
+	ErrorCodeTooManyJobs       = "too_many_jobs"
+)
+
+// ErrorCode returns the error code of the error, or an empty string if there is none.
+func ErrorCode(err error) string {
+	switch {
+	// the walk might be incomplete because of any of the other errors, but the client can resume it
+	case errors.Is(err, ErrWalkIncomplete):
+		return ErrorCodeWalkIncomplete
+	case errors.Is(err, ErrDeadlineExceeded):
+		return ErrorCodeDeadlineExceeded
+	case errors.Is(err, ErrTargetUnavailable):
+		return ErrorCodeTargetUnavailable
+	case errors.Is(err, ErrTimeout):
+		return ErrorCodeTimeout
+	case errors.Is(err, ErrLimitExceeded):
+		return ErrorCodeLimitExceeded
+	case errors.Is(err, ErrOidNotIncreasing):
+		return ErrorCodeOidNotIncreasing
+	case errors.Is(err, ErrQueueFull):
+		return ErrorCodeQueueFull
+	case errors.Is(err, ErrAdmissionTimeout):
+		return ErrorCodeAdmissionTimeout
+	case errors.Is(err, ErrTooManyJobs):
+		return ErrorCodeTooManyJobs
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,40 @@
+package snmpproxy_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{err: fmt.Errorf("%w (5s)", snmpproxy.ErrDeadlineExceeded), expected: snmpproxy.ErrorCodeDeadlineExceeded},
+		{err: fmt.Errorf("%w, some reason", snmpproxy.ErrLimitExceeded), expected: snmpproxy.ErrorCodeLimitExceeded},
+		{
+			err:      fmt.Errorf("%w: .1.2 after .1.3", snmpproxy.ErrOidNotIncreasing),
+			expected: snmpproxy.ErrorCodeOidNotIncreasing,
+		},
+		{err: fmt.Errorf("%w: .1.2.3", snmpproxy.ErrTimeout), expected: snmpproxy.ErrorCodeTimeout},
+		{
+			err:      fmt.Errorf("%w: 10.0.0.1 (after 3 consecutive timeouts)", snmpproxy.ErrTargetUnavailable),
+			expected: snmpproxy.ErrorCodeTargetUnavailable,
+		},
+		{
+			err:      fmt.Errorf("%w: .1.2 (%w, stopped after .1.2.3)", snmpproxy.ErrTimeout, snmpproxy.ErrWalkIncomplete),
+			expected: snmpproxy.ErrorCodeWalkIncomplete,
+		},
+		{err: fmt.Errorf("%w (limit 5)", snmpproxy.ErrTooManyJobs), expected: snmpproxy.ErrorCodeTooManyJobs},
+		{err: errors.New("no such object: .1.2.3"), expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			require.Equal(t, test.expected, snmpproxy.ErrorCode(test.err))
+		})
+	}
+}
@@ -0,0 +1,428 @@
+package snmpproxy
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+const (
+	// JobClientHeader identifies the client that starts the job (see JobSettings.MaxPerClient) if it's trusted
+	// (see JobSettings.TrustClientHeader), the remote address of the client is used otherwise.
+	JobClientHeader = "X-Client-Id"
+	// defaultJobRetention is used when JobSettings.Retention isn't set.
+	defaultJobRetention = 10 * time.Minute
+	// defaultMaxRetainedJobs is used when JobSettings.MaxRetained isn't set.
+	defaultMaxRetainedJobs = 10000
+	// callbackTimeout limits the delivery of the finished job to its callback URL.
+	callbackTimeout = 10 * time.Second
+)
+
+// JobSettings configure the asynchronous API requests, see JobsHandler.
+type JobSettings struct {
+	MaxPerClient uint          // jobs running at once per client (see JobClientHeader), zero means unlimited
+	Retention    time.Duration // finished jobs are kept this long, see defaultJobRetention
+	MaxRetained  uint          // finished jobs kept at once, the oldest are forgotten first, see defaultMaxRetainedJobs
+	// TrustClientHeader identifies the clients by JobClientHeader instead of their addresses. Any client may set
+	// the header, so it may only be enabled behind a trusted proxy that sets (or removes) it.
+	TrustClientHeader bool
+	// CallbackAllowlist lists the hosts and the networks (CIDRs, e.g. 10.1.0.0/16) the finished jobs may be delivered
+	// to. The listed hosts are trusted whatever their addresses are. If it's empty, the jobs may be delivered
+	// to the public addresses only (not to the loopback, private, link-local and other special ones).
+	CallbackAllowlist []string
+}
+
+// Validate checks the CallbackAllowlist.
+func (s JobSettings) Validate() error {
+	_, err := newCallbackPolicy(s.CallbackAllowlist)
+
+	return err
+}
+
+const (
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is the state of an asynchronous API request. Once it's finished, it's also delivered to the callback URL
+// of the job, if there is any.
+type Job struct {
+	Id       string     `json:"id"`
+	Status   string     `json:"status"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`  // the finished job is forgotten afterward
+	Response *Response  `json:"response,omitempty"` // the same as the synchronous API request would return
+}
+
+type job struct {
+	Job
+	client      string
+	callbackUrl string
+	cancel      context.CancelFunc
+	cancelled   bool
+	retained    *list.Element // of jobManager.retained, once the job is finished
+}
+
+// jobManager executes the API requests in the background, so that the long ones (e.g. walks through slow links)
+// don't depend on the timeouts of the HTTP clients. The SNMP operations of the jobs are admitted like any others
+// (see AdmissionLimits), and the jobs run until they are done, cancelled, or the API listener is closed.
+type jobManager struct {
+	listener *ApiListener
+	settings JobSettings
+	policy   callbackPolicy
+	client   *http.Client
+	mu       sync.Mutex
+	jobs     map[string]*job
+	running  map[string]uint // number of the running jobs by client
+	retained *list.List      // of the finished *job, the oldest first
+	inFlight prometheus.Gauge
+	finished *prometheus.CounterVec
+}
+
+func (m *jobManager) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	id := request.PathValue("id")
+
+	switch {
+	case id == "" && request.Method == "POST":
+		m.start(writer, request)
+	case id != "" && request.Method == "GET":
+		m.get(writer, id)
+	case id != "" && request.Method == "DELETE":
+		m.delete(writer, id)
+	default:
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *jobManager) start(writer http.ResponseWriter, request *http.Request) {
+	apiRequest, callbackUrl, err := m.parse(request)
+	if err != nil {
+		m.listener.logger.Debugw("invalid job API request", zap.Error(err))
+		writeJson(writer, http.StatusBadRequest, Response{Error: err.Error()})
+
+		return
+	}
+
+	client := m.clientOf(request)
+
+	m.mu.Lock()
+
+	if m.settings.MaxPerClient != 0 && m.running[client] >= m.settings.MaxPerClient {
+		m.mu.Unlock()
+
+		err = fmt.Errorf("%w (limit %d)", ErrTooManyJobs, m.settings.MaxPerClient)
+		m.listener.logger.Debugw("job rejected", zap.Error(err), "client", client)
+		writeJson(writer, http.StatusTooManyRequests, Response{Error: err.Error(), Code: ErrorCode(err)})
+
+		return
+	}
+
+	ctx, cancel := context.WithCancel(m.listener.ctx)
+	j := &job{
+		Job:         Job{Id: newJobId(), Status: JobRunning, Created: time.Now()},
+		client:      client,
+		callbackUrl: callbackUrl,
+		cancel:      cancel,
+	}
+
+	m.jobs[j.Id] = j
+	m.running[client]++
+	m.inFlight.Inc()
+
+	status := j.Job
+
+	m.mu.Unlock()
+
+	go m.execute(ctx, j, apiRequest)
+
+	writer.Header().Set("Location", path.Join(request.URL.Path, j.Id))
+	writeJson(writer, http.StatusAccepted, status)
+}
+
+func (m *jobManager) get(writer http.ResponseWriter, id string) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+
+	var status Job
+	if ok {
+		status = j.Job
+	}
+
+	m.mu.Unlock()
+
+	if !ok {
+		writeJson(writer, http.StatusNotFound, Response{Error: "job not found"})
+
+		return
+	}
+
+	writeJson(writer, http.StatusOK, status)
+}
+
+// delete cancels the job if it's still running, it's then kept (see JobCancelled) until the retention expires like
+// any other finished job. The finished jobs are forgotten right away.
+func (m *jobManager) delete(writer http.ResponseWriter, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		writeJson(writer, http.StatusNotFound, Response{Error: "job not found"})
+
+		return
+	}
+
+	if j.Status == JobRunning {
+		j.cancelled = true
+		j.cancel()
+	} else {
+		m.remove(j)
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (m *jobManager) execute(ctx context.Context, j *job, apiRequest *ApiRequest) {
+	defer j.cancel()
+
+	result, err := m.listener.requester.ExecuteRequest(ctx, apiRequest)
+
+	response := &Response{}
+
+	// partial results or incomplete walks, if the request failed
+	if result != nil {
+		response.Result, response.Cursors = result.Varbinds, result.Cursors
+	}
+
+	if err != nil {
+		m.listener.logger.Debugw("job failed", zap.Error(err), "job", j.Id, "request", apiRequest)
+
+		response.Error = err.Error()
+		response.Code = ErrorCode(err)
+	} else {
+		m.listener.logger.Debugw("job successful", "job", j.Id, "request", apiRequest)
+	}
+
+	finished := time.Now()
+	expires := finished.Add(m.settings.Retention)
+
+	m.mu.Lock()
+
+	switch {
+	case j.cancelled:
+		j.Status = JobCancelled
+	case err != nil:
+		j.Status = JobFailed
+	default:
+		j.Status = JobDone
+	}
+
+	j.Finished, j.Expires, j.Response = &finished, &expires, response
+
+	if m.running[j.client]--; m.running[j.client] == 0 {
+		delete(m.running, j.client)
+	}
+
+	m.inFlight.Dec()
+	m.finished.WithLabelValues(j.Status).Inc()
+
+	j.retained = m.retained.PushBack(j)
+	if uint(m.retained.Len()) > m.settings.MaxRetained {
+		m.remove(m.retained.Front().Value.(*job))
+	}
+
+	status := j.Job
+
+	m.mu.Unlock()
+
+	time.AfterFunc(m.settings.Retention, func() { m.forget(j) })
+
+	// nobody waits for the cancelled jobs
+	if j.callbackUrl != "" && status.Status != JobCancelled {
+		m.deliver(status, j.callbackUrl)
+	}
+}
+
+// deliver posts the finished job to its callback URL. It isn't retried, the client may still poll the job.
+func (m *jobManager) deliver(status Job, callbackUrl string) {
+	body, err := json.Marshal(status)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithTimeout(m.listener.ctx, callbackTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, "POST", callbackUrl, bytes.NewReader(body))
+	if err != nil {
+		panic(err) // the URL was validated already
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := m.client.Do(request)
+	if err == nil {
+		_ = response.Body.Close()
+
+		if response.StatusCode >= http.StatusMultipleChoices {
+			err = fmt.Errorf("unexpected status code %d", response.StatusCode)
+		}
+	}
+
+	if err != nil {
+		m.listener.logger.Warnw("failed to deliver job", zap.Error(err), "job", status.Id, "callbackUrl", callbackUrl)
+	}
+}
+
+func (m *jobManager) forget(j *job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.jobs[j.Id] == j {
+		m.remove(j)
+	}
+}
+
+// remove forgets the finished job.
+func (m *jobManager) remove(j *job) {
+	delete(m.jobs, j.Id)
+	m.retained.Remove(j.retained)
+}
+
+// parse reads, unmarshals and validates the API request of the job, and its optional callback_url.
+func (m *jobManager) parse(request *http.Request) (*ApiRequest, string, error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	apiRequest := &ApiRequest{}
+	if err = json.Unmarshal(body, apiRequest); err != nil {
+		return nil, "", err
+	}
+
+	var t struct {
+		CallbackUrl string `json:"callback_url"`
+	}
+
+	_ = json.Unmarshal(body, &t)
+
+	if t.CallbackUrl != "" {
+		var callbackUrl *url.URL
+
+		callbackUrl, err = url.Parse(t.CallbackUrl)
+		if err != nil || (callbackUrl.Scheme != "http" && callbackUrl.Scheme != "https") || callbackUrl.Host == "" {
+			return nil, "", fmt.Errorf("field callback_url must be an absolute HTTP(S) URL, got: %s", t.CallbackUrl)
+		}
+
+		if !m.policy.allowsUrl(callbackUrl) {
+			return nil, "", fmt.Errorf("field callback_url isn't allowed, got: %s", t.CallbackUrl)
+		}
+	}
+
+	if err = m.listener.applyDeadlineHeader(request, apiRequest); err != nil {
+		return nil, "", err
+	}
+
+	if apiRequest.Cache, err = parseCacheControl(request.Header.Get("Cache-Control")); err != nil {
+		return nil, "", err
+	}
+
+	if err = m.listener.validator.Validate(apiRequest); err != nil {
+		return nil, "", err
+	}
+
+	return apiRequest, t.CallbackUrl, nil
+}
+
+// clientOf identifies the client of the request, see JobClientHeader.
+func (m *jobManager) clientOf(request *http.Request) string {
+	if client := request.Header.Get(JobClientHeader); client != "" && m.settings.TrustClientHeader {
+		return client
+	}
+
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+
+	return host
+}
+
+func newJobId() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+func writeJson(writer http.ResponseWriter, statusCode int, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	_, _ = writer.Write(body)
+}
+
+// JobsHandler returns the handler of the asynchronous API requests (jobs), to be registered by Handle for both
+// the collection of the jobs (e.g. /snmp-proxy/jobs) and the single jobs (/snmp-proxy/jobs/{id}). POST of an API
+// request (with an optional callback_url) to the collection starts a job, GET of the job returns its Job, and DELETE
+// cancels the running job or forgets the finished one. The settings must be valid, see JobSettings.Validate.
+// It registers the metrics of the jobs, so it may only be called once.
+func (l *ApiListener) JobsHandler(settings JobSettings) http.Handler {
+	if settings.Retention == 0 {
+		settings.Retention = defaultJobRetention
+	}
+
+	if settings.MaxRetained == 0 {
+		settings.MaxRetained = defaultMaxRetainedJobs
+	}
+
+	policy, err := newCallbackPolicy(settings.CallbackAllowlist)
+	if err != nil {
+		panic(err)
+	}
+
+	return &jobManager{
+		listener: l,
+		settings: settings,
+		policy:   policy,
+		client:   policy.client(),
+		jobs:     make(map[string]*job),
+		running:  make(map[string]uint),
+		retained: list.New(),
+		inFlight: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "snmpproxy",
+			Name:      "jobs_running",
+			Help:      "Number of the asynchronous API requests being executed",
+		}),
+		finished: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "snmpproxy",
+			Name:      "jobs_finished_total",
+			Help:      "Number of the finished asynchronous API requests, by their status",
+		}, []string{"status"}),
+	}
+}
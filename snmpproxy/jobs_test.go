@@ -0,0 +1,354 @@
+package snmpproxy_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type jobsClient struct {
+	assert  *require.Assertions
+	handler http.Handler
+}
+
+func (c jobsClient) do(method, target, client, body string) (int, snmpproxy.Job, string) {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set(snmpproxy.JobClientHeader, client)
+
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	responseBody := read(response.Body)
+
+	var job snmpproxy.Job
+	if response.StatusCode == http.StatusOK || response.StatusCode == http.StatusAccepted {
+		c.assert.NoError(json.Unmarshal([]byte(responseBody), &job))
+	}
+
+	return response.StatusCode, job, responseBody
+}
+
+func (c jobsClient) get(id string) (int, snmpproxy.Job) {
+	statusCode, job, _ := c.do("GET", "/snmp-proxy/jobs/"+id, "", "")
+
+	return statusCode, job
+}
+
+func newJobsClient(
+	t *testing.T,
+	requester snmpproxy.Requester,
+	settings snmpproxy.JobSettings,
+) (*require.Assertions, jobsClient) {
+	assert := require.New(t)
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	listener := snmpproxy.NewApiListener(newValidator(), requester, zap.NewNop().Sugar(), "", 0)
+	handler := listener.JobsHandler(settings)
+
+	mux := http.NewServeMux()
+	mux.Handle("/snmp-proxy/jobs", handler)
+	mux.Handle("/snmp-proxy/jobs/{id}", handler)
+
+	return assert, jobsClient{assert: assert, handler: mux}
+}
+
+func TestJobs(t *testing.T) {
+	unblock := make(chan struct{})
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Run(func(mock.Arguments) {
+		<-unblock
+	}).Return(&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}}}, nil)
+
+	assert, client := newJobsClient(t, requester, snmpproxy.JobSettings{Retention: time.Millisecond * 100})
+
+	statusCode, job, _ := client.do("POST", "/snmp-proxy/jobs", "test", getRequestBody)
+	assert.Equal(http.StatusAccepted, statusCode)
+	assert.Equal(snmpproxy.JobRunning, job.Status)
+	assert.Len(job.Id, 32)
+
+	statusCode, job = client.get(job.Id)
+	assert.Equal(http.StatusOK, statusCode)
+	assert.Equal(snmpproxy.JobRunning, job.Status)
+	assert.Nil(job.Response)
+
+	close(unblock)
+
+	assert.Eventually(func() bool {
+		_, job = client.get(job.Id)
+
+		return job.Status != snmpproxy.JobRunning
+	}, time.Second, time.Millisecond)
+	assert.Equal(snmpproxy.JobDone, job.Status)
+	assert.Equal(&snmpproxy.Response{Result: [][]any{{".1.2.3", float64(123)}}}, job.Response)
+	assert.NotNil(job.Finished)
+	assert.Equal(job.Finished.Add(time.Millisecond*100), *job.Expires)
+
+	// the job is forgotten after the retention
+	assert.Eventually(func() bool {
+		statusCode, _ = client.get(job.Id)
+
+		return statusCode == http.StatusNotFound
+	}, time.Second, time.Millisecond)
+}
+
+func TestJobsMaxRetained(t *testing.T) {
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Times(3).
+		Return(&snmpproxy.Result{Varbinds: [][]any{{".1.2.3", 123}}}, nil)
+
+	assert, client := newJobsClient(t, requester, snmpproxy.JobSettings{MaxRetained: 2})
+
+	jobs := make([]snmpproxy.Job, 3)
+
+	for i := range jobs {
+		statusCode, job, _ := client.do("POST", "/snmp-proxy/jobs", "test", getRequestBody)
+		assert.Equal(http.StatusAccepted, statusCode)
+
+		assert.Eventually(func() bool {
+			_, job = client.get(job.Id)
+
+			return job.Status == snmpproxy.JobDone
+		}, time.Second, time.Millisecond)
+
+		jobs[i] = job
+	}
+
+	// the oldest finished job is forgotten first
+	statusCode, _ := client.get(jobs[0].Id)
+	assert.Equal(http.StatusNotFound, statusCode)
+
+	for _, job := range jobs[1:] {
+		statusCode, _ = client.get(job.Id)
+		assert.Equal(http.StatusOK, statusCode)
+	}
+}
+
+func TestJobsCallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist []string
+		host      string // of the callback URL, the server listens on the loopback
+		delivered bool
+	}{
+		{name: "allowed network", allowlist: []string{"127.0.0.0/8"}, host: "127.0.0.1", delivered: true},
+		{name: "allowed host", allowlist: []string{"example.com", "LocalHost"}, host: "localhost", delivered: true},
+		{name: "loopback by default", host: "localhost"},
+		{name: "network not allowed", allowlist: []string{"10.0.0.0/8"}, host: "localhost"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			callbacks := make(chan snmpproxy.Job, 1)
+
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				var job snmpproxy.Job
+				if err := json.NewDecoder(request.Body).Decode(&job); err == nil {
+					callbacks <- job
+				}
+
+				writer.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			requester := &mockRequester{}
+			defer requester.AssertExpectations(t)
+
+			requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Return(nil, snmpproxy.ErrTimeout)
+
+			assert, client := newJobsClient(t, requester, snmpproxy.JobSettings{CallbackAllowlist: test.allowlist})
+
+			callbackUrl := strings.Replace(server.URL, "127.0.0.1", test.host, 1) + "/done"
+			body := strings.Replace(getRequestBody, "{", `{"callback_url": "`+callbackUrl+`",`, 1)
+
+			statusCode, job, _ := client.do("POST", "/snmp-proxy/jobs", "test", body)
+			assert.Equal(http.StatusAccepted, statusCode)
+
+			if !test.delivered {
+				assert.Never(func() bool { return len(callbacks) != 0 }, time.Millisecond*200, time.Millisecond*10)
+
+				return
+			}
+
+			select {
+			case callback := <-callbacks:
+				assert.Equal(job.Id, callback.Id)
+				assert.Equal(snmpproxy.JobFailed, callback.Status)
+				assert.Equal(&snmpproxy.Response{Error: "timeout", Code: snmpproxy.ErrorCodeTimeout}, callback.Response)
+			case <-time.After(time.Second):
+				assert.Fail("the job wasn't delivered to the callback URL")
+			}
+
+			// the job can still be polled
+			statusCode, job = client.get(job.Id)
+			assert.Equal(http.StatusOK, statusCode)
+			assert.Equal(snmpproxy.JobFailed, job.Status)
+		})
+	}
+}
+
+func TestJobsCancelAndLimits(t *testing.T) {
+	cancelled := make(chan struct{})
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Times(3).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+		cancelled <- struct{}{}
+	}).Return(nil, context.Canceled)
+
+	assert, client := newJobsClient(t, requester, snmpproxy.JobSettings{MaxPerClient: 1, TrustClientHeader: true})
+
+	statusCode, first, _ := client.do("POST", "/snmp-proxy/jobs", "client1", getRequestBody)
+	assert.Equal(http.StatusAccepted, statusCode)
+
+	statusCode, _, body := client.do("POST", "/snmp-proxy/jobs", "client1", getRequestBody)
+	assert.Equal(http.StatusTooManyRequests, statusCode)
+	assert.Equal(`{"error":"too many jobs of the client are running (limit 1)","code":"too_many_jobs"}`, body)
+
+	// the limit is per client
+	statusCode, second, _ := client.do("POST", "/snmp-proxy/jobs", "client2", getRequestBody)
+	assert.Equal(http.StatusAccepted, statusCode)
+
+	statusCode, _, _ = client.do("DELETE", "/snmp-proxy/jobs/"+first.Id, "", "")
+	assert.Equal(http.StatusNoContent, statusCode)
+	<-cancelled
+
+	// the cancelled job is kept until the retention expires
+	assert.Eventually(func() bool {
+		_, first = client.get(first.Id)
+
+		return first.Status != snmpproxy.JobRunning
+	}, time.Second, time.Millisecond)
+	assert.Equal(snmpproxy.JobCancelled, first.Status)
+	assert.NotNil(first.Expires)
+
+	// the cancelled job doesn't count anymore
+	var third snmpproxy.Job
+
+	assert.Eventually(func() bool {
+		statusCode, third, _ = client.do("POST", "/snmp-proxy/jobs", "client1", getRequestBody)
+
+		return statusCode == http.StatusAccepted
+	}, time.Second, time.Millisecond)
+
+	for _, job := range []snmpproxy.Job{second, third} {
+		statusCode, _, _ = client.do("DELETE", "/snmp-proxy/jobs/"+job.Id, "", "")
+		assert.Equal(http.StatusNoContent, statusCode)
+		<-cancelled
+	}
+
+	// the finished job is forgotten
+	statusCode, _, _ = client.do("DELETE", "/snmp-proxy/jobs/"+first.Id, "", "")
+	assert.Equal(http.StatusNoContent, statusCode)
+
+	statusCode, _ = client.get(first.Id)
+	assert.Equal(http.StatusNotFound, statusCode)
+}
+
+func TestJobsClientHeaderNotTrusted(t *testing.T) {
+	cancelled := make(chan struct{})
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	requester.On("ExecuteRequest", mock.Anything, mock.Anything).Once().Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+		close(cancelled)
+	}).Return(nil, context.Canceled)
+
+	assert, client := newJobsClient(t, requester, snmpproxy.JobSettings{MaxPerClient: 1})
+
+	statusCode, job, _ := client.do("POST", "/snmp-proxy/jobs", "client1", getRequestBody)
+	assert.Equal(http.StatusAccepted, statusCode)
+
+	// both requests come from the same address
+	statusCode, _, _ = client.do("POST", "/snmp-proxy/jobs", "client2", getRequestBody)
+	assert.Equal(http.StatusTooManyRequests, statusCode)
+
+	statusCode, _, _ = client.do("DELETE", "/snmp-proxy/jobs/"+job.Id, "", "")
+	assert.Equal(http.StatusNoContent, statusCode)
+	<-cancelled
+}
+
+func TestJobsInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		body          string
+		expectedCode  int
+		expectedError string
+	}{
+		{
+			name:          "invalid callback URL",
+			method:        "POST",
+			target:        "/snmp-proxy/jobs",
+			body:          strings.Replace(getRequestBody, "{", `{"callback_url": "/relative",`, 1),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"error":"field callback_url must be an absolute HTTP(S) URL, got: /relative"}`,
+		},
+		{
+			name:          "callback URL not allowed",
+			method:        "POST",
+			target:        "/snmp-proxy/jobs",
+			body:          strings.Replace(getRequestBody, "{", `{"callback_url": "http://10.1.2.3/done",`, 1),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"error":"field callback_url isn't allowed, got: http://10.1.2.3/done"}`,
+		},
+		{
+			name:          "invalid request",
+			method:        "POST",
+			target:        "/snmp-proxy/jobs",
+			body:          strings.Replace(getRequestBody, `"timeout": 3`, `"timeout": 100`, 1),
+			expectedCode:  http.StatusBadRequest,
+			expectedError: `{"error":"maximum allowed timeout is 10s, got 1m40s"}`,
+		},
+		{name: "unknown job", method: "GET", target: "/snmp-proxy/jobs/abc", expectedCode: http.StatusNotFound},
+		{name: "GET of jobs", method: "GET", target: "/snmp-proxy/jobs", expectedCode: http.StatusMethodNotAllowed},
+		{
+			name:         "POST to job",
+			method:       "POST",
+			target:       "/snmp-proxy/jobs/abc",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert, client := newJobsClient(t, &mockRequester{}, snmpproxy.JobSettings{})
+
+			statusCode, _, body := client.do(test.method, test.target, "", test.body)
+			assert.Equal(test.expectedCode, statusCode)
+
+			if test.expectedError != "" {
+				assert.Equal(test.expectedError, body)
+			}
+		})
+	}
+}
+
+func TestJobSettingsValidate(t *testing.T) {
+	assert := require.New(t)
+
+	assert.NoError(snmpproxy.JobSettings{CallbackAllowlist: []string{"example.com", "10.0.0.0/8", "fd00::/8"}}.Validate())
+	assert.EqualError(
+		snmpproxy.JobSettings{CallbackAllowlist: []string{"10.0.0.0/33"}}.Validate(),
+		`invalid network of the callback allowlist: netip.ParsePrefix("10.0.0.0/33"): prefix length out of range`,
+	)
+}
@@ -0,0 +1,88 @@
+package snmpproxy
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// WalkLimits caps the amount of data returned by the walks, both for every single walk and for all the walks
+// of an ApiRequest together. Zero means no limit. The bytes are only an estimate of the size of the response.
+type WalkLimits struct {
+	MaxWalkVarbinds    uint64
+	MaxWalkBytes       uint64
+	MaxRequestVarbinds uint64
+	MaxRequestBytes    uint64
+}
+
+// walkLimiter counts the varbinds returned by the walks of a single ApiRequest.
+type walkLimiter struct {
+	limits   WalkLimits
+	varbinds atomic.Uint64
+	bytes    atomic.Uint64
+}
+
+// add counts the varbind of the walk. It returns an error wrapping ErrLimitExceeded if any of the limits is exceeded.
+func (l *walkLimiter) add(walk *walkUsage, oid string, value any) error {
+	size := uint64(len(oid) + valueSize(value))
+
+	walk.varbinds++
+	walk.bytes += size
+
+	return l.check(walk, walk.varbinds, walk.bytes, l.varbinds.Add(1), l.bytes.Add(size))
+}
+
+// checkPending checks the limits as if the pending varbinds of the walk (received, but not returned yet, e.g. those
+// buffered by the parallel walk) were returned already. They are counted by add once they are returned.
+func (l *walkLimiter) checkPending(walk *walkUsage, pending walkUsage) error {
+	return l.check(
+		walk,
+		walk.varbinds+pending.varbinds,
+		walk.bytes+pending.bytes,
+		l.varbinds.Load()+pending.varbinds,
+		l.bytes.Load()+pending.bytes,
+	)
+}
+
+func (l *walkLimiter) check(walk *walkUsage, walkVarbinds, walkBytes, varbinds, bytes uint64) error {
+	var reason string
+
+	switch {
+	case l.limits.MaxWalkVarbinds != 0 && walkVarbinds > l.limits.MaxWalkVarbinds:
+		reason = fmt.Sprintf("walk returned more than %d varbinds", l.limits.MaxWalkVarbinds)
+	case l.limits.MaxWalkBytes != 0 && walkBytes > l.limits.MaxWalkBytes:
+		reason = fmt.Sprintf("walk returned more than %d bytes", l.limits.MaxWalkBytes)
+	case l.limits.MaxRequestVarbinds != 0 && varbinds > l.limits.MaxRequestVarbinds:
+		reason = fmt.Sprintf("walks of the request returned more than %d varbinds", l.limits.MaxRequestVarbinds)
+	case l.limits.MaxRequestBytes != 0 && bytes > l.limits.MaxRequestBytes:
+		reason = fmt.Sprintf("walks of the request returned more than %d bytes", l.limits.MaxRequestBytes)
+	default:
+		return nil
+	}
+
+	return fmt.Errorf("%w, %s: %s", ErrLimitExceeded, reason, walk.oid)
+}
+
+// walkUsage counts the varbinds returned by a single walk.
+type walkUsage struct {
+	oid      string
+	varbinds uint64
+	bytes    uint64
+}
+
+func valueSize(value any) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	default:
+		return 8
+	}
+}
+
+// pduSize estimates the size of the varbind before it's formatted, see valueSize.
+func pduSize(dataUnit gosnmp.SnmpPDU) uint64 {
+	return uint64(len(dataUnit.Name) + valueSize(dataUnit.Value))
+}
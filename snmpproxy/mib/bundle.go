@@ -0,0 +1,50 @@
+package mib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// BundleVersion is increased whenever the format of the MIB bundle changes in a backwards incompatible way.
+const BundleVersion = 1
+
+type bundle struct {
+	Version int     `json:"version"`
+	Objects Objects `json:"objects"`
+}
+
+// BundleMibParser loads MIB objects from a bundle previously created by WriteBundle, so that binaries built
+// without net-snmp can still use the MIB information.
+type BundleMibParser struct {
+	path string
+}
+
+func (p *BundleMibParser) Parse() (Objects, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MIB bundle: %w", err)
+	}
+	defer file.Close()
+
+	var b bundle
+
+	if err = json.NewDecoder(file).Decode(&b); err != nil {
+		return nil, fmt.Errorf("failed to decode MIB bundle %s: %w", p.path, err)
+	}
+
+	if b.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported MIB bundle version %d, expected %d", b.Version, BundleVersion)
+	}
+
+	return b.Objects, nil
+}
+
+func NewBundleMibParser(path string) *BundleMibParser {
+	return &BundleMibParser{path: path}
+}
+
+func WriteBundle(writer io.Writer, objects Objects) error {
+	return json.NewEncoder(writer).Encode(bundle{Version: BundleVersion, Objects: objects})
+}
@@ -0,0 +1,67 @@
+package mib_test
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleMibParser_Parse(t *testing.T) {
+	assert := require.New(t)
+
+	objects := mib.Objects{
+		".1.3.6.1.2.1.2.2":      {Name: "ifTable"},
+		".1.3.6.1.2.1.2.2.1.2":  {Name: "ifDescr", DisplayHint: mib.DisplayHintString},
+		".1.3.6.1.2.1.2.2.1.8":  {Name: "ifOperStatus", Enums: map[int]string{1: "up", 2: "down"}},
+		".1.3.6.1.2.1.4.22.1.2": {Name: "ipNetToMediaPhysAddress", DisplayHint: mib.DisplayHintHexadecimal},
+	}
+
+	buffer := bytes.Buffer{}
+	assert.NoError(mib.WriteBundle(&buffer, objects))
+
+	bundlePath := path.Join(t.TempDir(), "bundle.json")
+	assert.NoError(os.WriteFile(bundlePath, buffer.Bytes(), 0o600))
+
+	parsed, err := mib.NewBundleMibParser(bundlePath).Parse()
+	assert.NoError(err)
+	assert.Equal(objects, parsed)
+}
+
+func TestBundleMibParser_ParseError(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{
+			name:    "invalid json",
+			content: "{",
+			err:     "unexpected EOF",
+		},
+		{
+			name:    "unsupported version",
+			content: `{"version":999,"objects":{}}`,
+			err:     "unsupported MIB bundle version 999, expected 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundlePath := path.Join(t.TempDir(), "bundle.json")
+			require.NoError(t, os.WriteFile(bundlePath, []byte(test.content), 0o600))
+
+			objects, err := mib.NewBundleMibParser(bundlePath).Parse()
+			require.Nil(t, objects)
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestBundleMibParser_ParseMissingFile(t *testing.T) {
+	objects, err := mib.NewBundleMibParser("/non/existent/bundle.json").Parse()
+	require.Nil(t, objects)
+	require.EqualError(t, err, "failed to open MIB bundle: open /non/existent/bundle.json: no such file or directory")
+}
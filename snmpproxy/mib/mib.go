@@ -1,6 +1,8 @@
 package mib
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -15,24 +17,147 @@ const (
 
 type DisplayHints map[string]DisplayHint
 
+// Object holds the information about a single MIB node that the proxy cares about.
+type Object struct {
+	Name              string         `json:"name"`
+	Module            string         `json:"module,omitempty"`
+	Type              string         `json:"type,omitempty"`
+	TextualConvention string         `json:"textual_convention,omitempty"`
+	Hint              string         `json:"hint,omitempty"` // DISPLAY-HINT as written in the MIB
+	DisplayHint       DisplayHint    `json:"display_hint,omitempty"`
+	Enums             map[int]string `json:"enums,omitempty"`
+	Units             string         `json:"units,omitempty"`
+	Access            string         `json:"access,omitempty"`
+	Description       string         `json:"description,omitempty"`
+}
+
+// FullName returns the name of the object qualified by the name of its module, e.g. IF-MIB::ifDescr.
+func (o Object) FullName() string {
+	if o.Module == "" {
+		return o.Name
+	}
+
+	return o.Module + "::" + o.Name
+}
+
+// Objects maps OIDs (with the leading dot) to their MIB Object.
+type Objects map[string]Object
+
+func (o Objects) DisplayHints() DisplayHints {
+	displayHints := make(DisplayHints)
+
+	for oid, object := range o {
+		if object.DisplayHint != DisplayHintUnknown {
+			displayHints[oid] = object.DisplayHint
+		}
+	}
+
+	return displayHints
+}
+
 type Parser interface {
-	Parse() (DisplayHints, error)
+	Parse() (Objects, error)
 }
 
 type DataProvider struct {
-	displayHints DisplayHints
+	tree  *oidTree
+	names map[string]string // name (both plain and qualified by the module) -> OID
 }
 
 func (p *DataProvider) GetDisplayHint(oid string) DisplayHint {
-	for length := len(oid); length > 7; length = strings.LastIndex(oid[:length], ".") {
-		if displayHint, ok := p.displayHints[oid[:length]]; ok {
-			return displayHint
+	node, _ := p.tree.find(oid)
+
+	return node.displayHint
+}
+
+// GetObject finds the closest MIB object to which the given OID belongs. It returns the OID of the object
+// and the remaining suffix of the given OID (e.g. table index), which is empty if the object matches exactly.
+func (p *DataProvider) GetObject(oid string) (objectOid string, suffix string, object Object, ok bool) {
+	node, _ := p.tree.find(oid)
+	if node.closest == nil {
+		return "", "", Object{}, false
+	}
+
+	return node.closest.oid, oid[len(node.closest.oid):], *node.closest.object, true
+}
+
+// Translate converts the OID into a name, e.g. .1.3.6.1.2.1.2.2.1.2.5 -> IF-MIB::ifDescr.5.
+func (p *DataProvider) Translate(oid string) (string, error) {
+	_, suffix, object, ok := p.GetObject(oid)
+	if !ok {
+		return "", fmt.Errorf("unknown OID: %s", oid)
+	}
+
+	return object.FullName() + suffix, nil
+}
+
+// Resolve converts the name into an OID, e.g. IF-MIB::ifDescr.5 or ifDescr.5 -> .1.3.6.1.2.1.2.2.1.2.5.
+func (p *DataProvider) Resolve(name string) (string, error) {
+	var suffix string
+
+	// the module name may contain dots, so only look for the suffix after the object name
+	moduleSeparator := strings.LastIndex(name, "::") + 1
+	if i := strings.IndexByte(name[moduleSeparator:], '.'); i != -1 {
+		name, suffix = name[:moduleSeparator+i], name[moduleSeparator+i:]
+	}
+
+	oid, ok := p.names[name]
+	if !ok {
+		return "", fmt.Errorf("unknown name: %s", name)
+	}
+
+	return oid + suffix, nil
+}
+
+// GetChildren returns the OIDs of direct children of the given OID, ordered by their last sub-identifier.
+func (p *DataProvider) GetChildren(oid string) []string {
+	node, ok := p.tree.find(oid)
+	if !ok {
+		return nil
+	}
+
+	children := make([]string, 0, len(node.children))
+
+	for _, child := range node.children {
+		if child.object != nil {
+			children = append(children, child.oid)
 		}
 	}
 
-	return DisplayHintUnknown
+	return children
 }
 
-func NewDataProvider(displayHints DisplayHints) *DataProvider {
-	return &DataProvider{displayHints: displayHints}
+func NewDataProvider(objects Objects) *DataProvider {
+	provider := &DataProvider{tree: newOidTree(objects), names: make(map[string]string, len(objects)*2)}
+
+	for oid, object := range objects {
+		for _, name := range []string{object.Name, object.FullName()} {
+			// names might not be unique across modules, so prefer the first one in the tree to be deterministic
+			if current, ok := provider.names[name]; !ok || compareOids(oid, current) < 0 {
+				provider.names[name] = oid
+			}
+		}
+	}
+
+	return provider
+}
+
+func compareOids(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "."), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "."), ".")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aPart, _ := strconv.ParseUint(aParts[i], 10, 32)
+		bPart, _ := strconv.ParseUint(bParts[i], 10, 32)
+
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return len(aParts) - len(bParts)
 }
@@ -1,6 +1,7 @@
 package mib_test
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
@@ -9,51 +10,185 @@ import (
 
 func TestMibDataProvider_GetStringType(t *testing.T) {
 	tests := []struct {
-		name        string
-		stringTypes mib.DisplayHints
-		oid         string
-		expected    mib.DisplayHint
+		name     string
+		objects  mib.Objects
+		oid      string
+		expected mib.DisplayHint
 	}{
 		{
 			name: "exact match",
-			stringTypes: mib.DisplayHints{
-				".1.2.3.1.1.5.15.2": mib.DisplayHintString,
-				".1.2.3.1.1.5.15":   mib.DisplayHintHexadecimal,
+			objects: mib.Objects{
+				".1.2.3.1.1.5.15.2": {DisplayHint: mib.DisplayHintString},
+				".1.2.3.1.1.5.15":   {DisplayHint: mib.DisplayHintHexadecimal},
 			},
 			oid:      ".1.2.3.1.1.5.15.2",
 			expected: mib.DisplayHintString,
 		},
 		{
 			name: "parent matched",
-			stringTypes: mib.DisplayHints{
-				".1.2.3.1.1.5.15.22": mib.DisplayHintHexadecimal,
-				".1.2.3.1.1.5.15":    mib.DisplayHintString,
+			objects: mib.Objects{
+				".1.2.3.1.1.5.15.22": {DisplayHint: mib.DisplayHintHexadecimal},
+				".1.2.3.1.1.5.15":    {DisplayHint: mib.DisplayHintString},
 			},
 			oid:      ".1.2.3.1.1.5.15.2",
 			expected: mib.DisplayHintString,
 		},
 		{
 			name: "parent matched (multiple steps back)",
-			stringTypes: mib.DisplayHints{
-				".1.2.3.1.1.5.15.22": mib.DisplayHintString,
-				".1.2.3.1.1.5.1":     mib.DisplayHintString,
-				".1.2.3.1.1.1":       mib.DisplayHintString,
-				".1.2.3.1.11":        mib.DisplayHintString,
-				".1.2.3.1":           mib.DisplayHintHexadecimal,
+			objects: mib.Objects{
+				".1.2.3.1.1.5.15.22": {DisplayHint: mib.DisplayHintString},
+				".1.2.3.1.1.5.1":     {DisplayHint: mib.DisplayHintString},
+				".1.2.3.1.1.1":       {DisplayHint: mib.DisplayHintString},
+				".1.2.3.1.11":        {DisplayHint: mib.DisplayHintString},
+				".1.2.3.1":           {DisplayHint: mib.DisplayHintHexadecimal},
 			},
 			oid:      ".1.2.3.1.1.5.15.2",
 			expected: mib.DisplayHintHexadecimal,
 		},
 		{
-			name:        "OID is too short to consider -> unknown type",
-			stringTypes: mib.DisplayHints{".1.2.3": mib.DisplayHintHexadecimal},
-			oid:         ".1.2.3",
-			expected:    mib.DisplayHintUnknown,
+			name:     "OID is too short to consider -> unknown type",
+			objects:  mib.Objects{".1.2.3": {DisplayHint: mib.DisplayHintHexadecimal}},
+			oid:      ".1.2.3",
+			expected: mib.DisplayHintUnknown,
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			require.Equal(t, test.expected, mib.NewDataProvider(test.stringTypes).GetDisplayHint(test.oid))
+			require.Equal(t, test.expected, mib.NewDataProvider(test.objects).GetDisplayHint(test.oid))
 		})
 	}
 }
+
+func newIfMibDataProvider() *mib.DataProvider {
+	return mib.NewDataProvider(
+		mib.Objects{
+			".1":                    {Name: "iso", Module: "SNMPv2-SMI"},
+			".1.3.6.1.2.1.2":        {Name: "interfaces", Module: "IF-MIB"},
+			".1.3.6.1.2.1.2.2":      {Name: "ifTable", Module: "IF-MIB"},
+			".1.3.6.1.2.1.2.2.1":    {Name: "ifEntry", Module: "IF-MIB"},
+			".1.3.6.1.2.1.2.2.1.2":  {Name: "ifDescr", Module: "IF-MIB", DisplayHint: mib.DisplayHintString},
+			".1.3.6.1.2.1.2.2.1.10": {Name: "ifInOctets", Module: "IF-MIB"},
+			".1.3.6.1.2.1.2.2.1.8":  {Name: "ifOperStatus", Module: "IF-MIB", Enums: map[int]string{1: "up"}},
+			".1.3.6.1.4.1.9.2":      {Name: "ifDescr", Module: "OTHER-MIB"},
+		},
+	)
+}
+
+func TestMibDataProvider_Translate(t *testing.T) {
+	tests := []struct {
+		oid      string
+		expected string
+		err      string
+	}{
+		{oid: ".1.3.6.1.2.1.2.2.1.2", expected: "IF-MIB::ifDescr"},
+		{oid: ".1.3.6.1.2.1.2.2.1.2.5", expected: "IF-MIB::ifDescr.5"},
+		{oid: ".1.3.6.1.2.1.2.2.1.20.1", expected: "IF-MIB::ifEntry.20.1"},
+		{oid: ".1.3.6", expected: "SNMPv2-SMI::iso.3.6"},
+		{oid: ".1.3.6.1.2.1.9.1", expected: "SNMPv2-SMI::iso.3.6.1.2.1.9.1"},
+		{oid: ".1.3.6.1.2.1.2.21", expected: "IF-MIB::interfaces.21"},
+		{oid: ".2.1", err: "unknown OID: .2.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.oid, func(t *testing.T) {
+			name, err := newIfMibDataProvider().Translate(test.oid)
+
+			if test.err == "" {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, name)
+			} else {
+				require.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestMibDataProvider_Resolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		err      string
+	}{
+		{name: "ifDescr", expected: ".1.3.6.1.2.1.2.2.1.2"},
+		{name: "ifDescr.5", expected: ".1.3.6.1.2.1.2.2.1.2.5"},
+		{name: "IF-MIB::ifDescr.5.6", expected: ".1.3.6.1.2.1.2.2.1.2.5.6"},
+		{name: "OTHER-MIB::ifDescr", expected: ".1.3.6.1.4.1.9.2"},
+		{name: "ifWhatever.1", err: "unknown name: ifWhatever"},
+		{name: "OTHER-MIB::ifTable", err: "unknown name: OTHER-MIB::ifTable"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oid, err := newIfMibDataProvider().Resolve(test.name)
+
+			if test.err == "" {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, oid)
+			} else {
+				require.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestMibDataProvider_GetObject(t *testing.T) {
+	assert := require.New(t)
+
+	provider := newIfMibDataProvider()
+
+	oid, suffix, object, ok := provider.GetObject(".1.3.6.1.2.1.2.2.1.8.3")
+	assert.True(ok)
+	assert.Equal(".1.3.6.1.2.1.2.2.1.8", oid)
+	assert.Equal(".3", suffix)
+	assert.Equal(mib.Object{Name: "ifOperStatus", Module: "IF-MIB", Enums: map[int]string{1: "up"}}, object)
+
+	_, _, _, ok = provider.GetObject(".2")
+	assert.False(ok)
+}
+
+func TestMibDataProvider_GetChildren(t *testing.T) {
+	assert := require.New(t)
+
+	provider := newIfMibDataProvider()
+
+	assert.Equal(
+		[]string{".1.3.6.1.2.1.2.2.1.2", ".1.3.6.1.2.1.2.2.1.8", ".1.3.6.1.2.1.2.2.1.10"},
+		provider.GetChildren(".1.3.6.1.2.1.2.2.1"),
+	)
+	assert.Empty(provider.GetChildren(".1.3.6.1.2.1.2.2.1.2"))
+	assert.Empty(provider.GetChildren(".1.3.6.1.2.1.2.2.1.2.1"))
+}
+
+func BenchmarkMibDataProvider_GetDisplayHint(b *testing.B) {
+	objects := make(mib.Objects)
+
+	// roughly the size of the MIB tree with a common set of MIBs installed
+	for i := range 200 {
+		for j := range 100 {
+			objects[".1.3.6.1.4.1."+strconv.Itoa(i)+".1.1."+strconv.Itoa(j)] = mib.Object{
+				Name:        "object" + strconv.Itoa(j),
+				DisplayHint: mib.DisplayHint(j%3 + 1),
+			}
+		}
+	}
+
+	provider := mib.NewDataProvider(objects)
+
+	oids := make([]string, 0, 1000)
+	for i := range cap(oids) {
+		object := ".1.3.6.1.4.1." + strconv.Itoa(i%200) + ".1.1." + strconv.Itoa(i%100)
+
+		switch i % 3 {
+		case 0: // a simple index
+			oids = append(oids, object+"."+strconv.Itoa(i))
+		case 1: // a long index, e.g. an IP address or a MAC address
+			oids = append(oids, object+".1.4.192.168.1."+strconv.Itoa(i%256))
+		default: // an OID not known by any MIB
+			oids = append(oids, ".1.3.6.1.4.1.9999.1.2.3.4."+strconv.Itoa(i))
+		}
+	}
+
+	b.ResetTimer()
+
+	for i := range b.N {
+		provider.GetDisplayHint(oids[i%len(oids)])
+	}
+}
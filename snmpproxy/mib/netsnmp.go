@@ -7,80 +7,67 @@ package mib
 #cgo CFLAGS: -I/usr/local/include
 #include <net-snmp/net-snmp-config.h>
 #include <net-snmp/mib_api.h>
+#include <net-snmp/library/default_store.h>
+#include <stdlib.h>
 */
 import "C"
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"unsafe"
 
 	"go.uber.org/zap"
-	"golang.org/x/sys/unix"
 )
 
+const moduleNameBufferSize = 256
+
 // This parser was inspired by https://github.com/prometheus/snmp_exporter/tree/master/generator
 type NetsnmpMibParser struct {
 	logger        *zap.SugaredLogger
 	strictParsing bool
+
+	// net-snmp keeps the MIB tree in a global state, so parsing mustn't run concurrently,
+	// and the previous tree must be released before the MIBs can be parsed again.
+	mu          sync.Mutex
+	initialized bool
 }
 
-func (p *NetsnmpMibParser) Parse() (DisplayHints, error) {
+func (p *NetsnmpMibParser) Parse() (Objects, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	err := os.Setenv("MIBS", "ALL")
 	if err != nil {
 		return nil, fmt.Errorf("failed to set ENV variable: %w", err)
 	}
 
+	// Keep the descriptions of the objects, they are thrown away by default
+	C.netsnmp_ds_set_boolean(C.NETSNMP_DS_LIBRARY_ID, C.NETSNMP_DS_LIB_SAVE_MIB_DESCRS, C.int(1))
+
 	p.logger.Infow("loading MIB files", "source", C.GoString(C.netsnmp_get_mib_directory()))
 
-	// Redirect stderr to a pipe to catch netsnmp errors
-	r, w, err := os.Pipe()
-	if err != nil {
-		return nil, fmt.Errorf("error creating pipe: %w", err)
-	}
-	defer r.Close()
-	defer w.Close()
+	var messages strings.Builder
 
-	originalStderrFd, err := unix.Dup(unix.Stderr)
-	if err != nil {
-		return nil, err
-	}
+	stopCapture := captureNetsnmpLog(func(message string) {
+		messages.WriteString(message)
+	})
 
-	err = unix.Dup2(int(w.Fd()), unix.Stderr)
-	if err != nil {
-		return nil, err
+	// Initialize the MIBs
+	if p.initialized {
+		C.shutdown_mib()
 	}
 
-	outChan := make(chan string)
-	errChan := make(chan error)
-
-	go func() {
-		data, err := io.ReadAll(r)
-		if err != nil {
-			errChan <- fmt.Errorf("error reading from pipe: %w", err)
-
-			return
-		}
-
-		close(errChan)
-		outChan <- string(data)
-	}()
-
-	// Initialize the MIBs
 	C.netsnmp_init_mib()
 
-	// Restore stderr
-	_ = w.Close()
-	_ = unix.Dup2(originalStderrFd, unix.Stderr)
-	_ = unix.Close(originalStderrFd)
+	p.initialized = true
 
-	if err := <-errChan; err != nil {
-		return nil, err
-	}
+	stopCapture()
 
-	output := strings.TrimSpace(<-outChan)
+	output := strings.TrimSpace(messages.String())
 	if output != "" {
 		if p.strictParsing {
 			return nil, fmt.Errorf("netsnmp: %s", output)
@@ -89,37 +76,129 @@ func (p *NetsnmpMibParser) Parse() (DisplayHints, error) {
 		p.logger.Warnw("encountered errors during MIB parsing", "errors", output)
 	}
 
-	displayHints := make(DisplayHints)
+	objects := make(Objects)
 
-	p.findStringTypesDisplayHints(displayHints, C.get_tree_head(), "")
+	moduleBuffer := (*C.char)(C.malloc(moduleNameBufferSize))
+	defer C.free(unsafe.Pointer(moduleBuffer))
 
-	return displayHints, nil
-}
+	p.collectObjects(objects, C.get_tree_head(), "", moduleBuffer)
 
-func (p *NetsnmpMibParser) findStringTypesDisplayHints(displayHints DisplayHints, t *C.struct_tree, oid string) {
-	if t.child_list == nil && t._type != 2 { // we only care about OctetStr type
-		return
-	}
+	return objects, nil
+}
 
+func (p *NetsnmpMibParser) collectObjects(objects Objects, t *C.struct_tree, oid string, moduleBuffer *C.char) {
 	oid = oid + "." + strconv.Itoa(int(t.subid))
 
-	if t.child_list == nil {
-		switch C.GoString(C.get_tc_descriptor(t.tc_index)) {
+	object := Object{
+		Name:              C.GoString(t.label),
+		Module:            C.GoString(C.module_name(t.modid, moduleBuffer)),
+		Type:              p.getTypeName(int(t._type)),
+		TextualConvention: C.GoString(C.get_tc_descriptor(t.tc_index)),
+		Hint:              C.GoString(t.hint),
+		Units:             C.GoString(t.units),
+		Access:            p.getAccessName(int(t.access)),
+		Description:       strings.TrimSpace(C.GoString(t.description)),
+	}
+
+	if t.child_list == nil && t._type == 2 { // display hints only make sense for OctetStr type
+		switch object.TextualConvention {
 		case "DisplayString", "SnmpAdminString", "InetAddress", "OwnerString":
-			displayHints[oid] = DisplayHintString
+			object.DisplayHint = DisplayHintString
 		case "PhysAddress":
-			displayHints[oid] = DisplayHintHexadecimal
+			object.DisplayHint = DisplayHintHexadecimal
 		case "DateAndTime":
-			displayHints[oid] = DisplayHintDateAndTime
+			object.DisplayHint = DisplayHintDateAndTime
+		}
+	}
+
+	for enum := t.enums; enum != nil; enum = enum.next {
+		if object.Enums == nil {
+			object.Enums = make(map[int]string)
 		}
 
-		return
+		object.Enums[int(enum.value)] = C.GoString(enum.label)
+	}
+
+	objects[oid] = object
+
+	for next := t.child_list; next != nil; next = next.next_peer {
+		p.collectObjects(objects, next, oid, moduleBuffer)
 	}
+}
+
+// getTypeName converts the TYPE_* constants from net-snmp/library/parse.h to the names used in the MIBs.
+func (*NetsnmpMibParser) getTypeName(netsnmpType int) string {
+	switch netsnmpType {
+	case 1:
+		return "OBJECT IDENTIFIER"
+	case 2:
+		return "OCTET STRING"
+	case 3:
+		return "INTEGER"
+	case 4:
+		return "NetworkAddress"
+	case 5:
+		return "IpAddress"
+	case 6:
+		return "Counter32"
+	case 7:
+		return "Gauge32"
+	case 8:
+		return "TimeTicks"
+	case 9:
+		return "Opaque"
+	case 10:
+		return "NULL"
+	case 11:
+		return "Counter64"
+	case 12:
+		return "BITS"
+	case 13:
+		return "NsapAddress"
+	case 14:
+		return "UInteger32"
+	case 15:
+		return "Unsigned32"
+	case 16:
+		return "Integer32"
+	case 20:
+		return "TRAP-TYPE"
+	case 21:
+		return "NOTIFICATION-TYPE"
+	case 22:
+		return "OBJECT-GROUP"
+	case 23:
+		return "NOTIFICATION-GROUP"
+	case 24:
+		return "MODULE-IDENTITY"
+	case 25:
+		return "AGENT-CAPABILITIES"
+	case 26:
+		return "MODULE-COMPLIANCE"
+	case 27:
+		return "OBJECT-IDENTITY"
+	default:
+		return ""
+	}
+}
 
-	next := t.child_list
-	for next != nil {
-		p.findStringTypesDisplayHints(displayHints, next, oid)
-		next = next.next_peer
+// getAccessName converts the MIB_ACCESS_* constants from net-snmp/library/parse.h to the names used in the MIBs.
+func (*NetsnmpMibParser) getAccessName(access int) string {
+	switch access {
+	case 18:
+		return "read-only"
+	case 19:
+		return "read-write"
+	case 20:
+		return "write-only"
+	case 21:
+		return "not-accessible"
+	case 48:
+		return "read-create"
+	case 67:
+		return "accessible-for-notify"
+	default:
+		return ""
 	}
 }
 
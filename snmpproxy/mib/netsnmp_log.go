@@ -0,0 +1,67 @@
+//go:build !nonetsnmp
+
+package mib
+
+/*
+#include <net-snmp/net-snmp-config.h>
+#include <net-snmp/net-snmp-includes.h>
+
+extern int netsnmpLogCallback(int majorId, int minorId, void *serverArg, void *clientArg);
+*/
+import "C"
+
+import (
+	"os"
+	"sync"
+	"unsafe"
+)
+
+var (
+	netsnmpLogOnce sync.Once
+	netsnmpLogMu   sync.Mutex
+	netsnmpLogFn   func(message string) // receives the messages of net-snmp, see captureNetsnmpLog
+)
+
+// netsnmpLogCallback is the SNMPCallback of the logging of net-snmp.
+//
+//export netsnmpLogCallback
+func netsnmpLogCallback(majorId, minorId C.int, serverArg, clientArg unsafe.Pointer) C.int {
+	message := C.GoString((*C.struct_snmp_log_message)(serverArg).msg)
+
+	netsnmpLogMu.Lock()
+	defer netsnmpLogMu.Unlock()
+
+	if netsnmpLogFn != nil {
+		netsnmpLogFn(message)
+	} else {
+		_, _ = os.Stderr.WriteString(message)
+	}
+
+	return C.SNMPERR_SUCCESS
+}
+
+// captureNetsnmpLog passes the messages logged by net-snmp to the function until the returned function is called,
+// they are written to stderr otherwise. The messages are received by the logging callback of net-snmp rather than
+// by redirecting stderr, so that nothing else written to stderr meanwhile (e.g. the logs of the running requests)
+// is mistaken for them.
+func captureNetsnmpLog(fn func(message string)) func() {
+	netsnmpLogOnce.Do(func() {
+		C.snmp_register_callback(
+			C.SNMP_CALLBACK_LIBRARY,
+			C.SNMP_CALLBACK_LOGGING,
+			(*C.SNMPCallback)(C.netsnmpLogCallback),
+			nil,
+		)
+		C.netsnmp_register_loghandler(C.NETSNMP_LOGHANDLER_CALLBACK, C.LOG_DEBUG)
+	})
+
+	netsnmpLogMu.Lock()
+	netsnmpLogFn = fn
+	netsnmpLogMu.Unlock()
+
+	return func() {
+		netsnmpLogMu.Lock()
+		netsnmpLogFn = nil
+		netsnmpLogMu.Unlock()
+	}
+}
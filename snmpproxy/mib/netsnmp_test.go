@@ -14,10 +14,25 @@ func TestNetsnmpMibParser_Parse(t *testing.T) {
 	assert := require.New(t)
 
 	mibParser := mib.NewNetsnmpMibParser(zap.NewNop().Sugar(), false)
-	displayHints, err := mibParser.Parse()
+	objects, err := mibParser.Parse()
 
 	assert.NoError(err)
-	assert.NotEmpty(displayHints)
+	assert.NotEmpty(objects)
+	assert.Equal(mib.DisplayHintString, objects[".1.3.6.1.2.1.2.2.1.2"].DisplayHint)
+	assert.Equal(mib.DisplayHintHexadecimal, objects[".1.3.6.1.2.1.4.22.1.2"].DisplayHint)
+	assert.Equal("ifDescr", objects[".1.3.6.1.2.1.2.2.1.2"].Name)
+	assert.Equal("ifTable", objects[".1.3.6.1.2.1.2.2"].Name)
+	assert.Equal("up", objects[".1.3.6.1.2.1.2.2.1.8"].Enums[1])
+
+	ifDescr := objects[".1.3.6.1.2.1.2.2.1.2"]
+	assert.Equal("IF-MIB", ifDescr.Module)
+	assert.Equal("OCTET STRING", ifDescr.Type)
+	assert.Equal("DisplayString", ifDescr.TextualConvention)
+	assert.Equal("255a", ifDescr.Hint)
+	assert.Equal("read-only", ifDescr.Access)
+	assert.NotEmpty(ifDescr.Description)
+
+	displayHints := objects.DisplayHints()
 	assert.Equal(mib.DisplayHintString, displayHints[".1.3.6.1.2.1.2.2.1.2"])
-	assert.Equal(mib.DisplayHintHexadecimal, displayHints[".1.3.6.1.2.1.4.22.1.2"])
+	assert.NotContains(displayHints, ".1.3.6.1.2.1.2.2")
 }
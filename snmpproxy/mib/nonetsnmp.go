@@ -9,7 +9,7 @@ import (
 type NopMibParser struct {
 }
 
-func (n NopMibParser) Parse() (DisplayHints, error) {
+func (n NopMibParser) Parse() (Objects, error) {
 	return nil, nil
 }
 
@@ -12,7 +12,7 @@ import (
 
 func TestNopMibParser_Parse(t *testing.T) {
 	parser := mib.NewNetsnmpMibParser(nil, false)
-	hints, err := parser.Parse()
-	require.Nil(t, hints)
+	objects, err := parser.Parse()
+	require.Nil(t, objects)
 	require.Nil(t, err)
 }
@@ -0,0 +1,212 @@
+package mib
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// oidTree is a prefix tree over the sub-identifiers of the OIDs of MIB objects. It allows to find the closest
+// matching object for an OID in a single pass over the OID, without any allocations.
+type oidTree struct {
+	root oidTreeNode
+}
+
+type oidTreeNode struct {
+	subId    uint32
+	rest     string // chains of nodes without objects are merged, this holds the sub-identifiers of the merged nodes
+	oid      string
+	object   *Object
+	children []oidTreeNode // ordered by subId
+
+	// precomputed results of the lookups, inherited from the closest ancestor if not set for the node itself
+	closest     *oidTreeNode // closest node with an object
+	displayHint DisplayHint
+}
+
+func (n *oidTreeNode) child(subId uint32) *oidTreeNode {
+	children := n.children
+
+	// manual binary search, this is the hot path of the value formatting
+	low, high := 0, len(children)
+	for low < high {
+		middle := int(uint(low+high) >> 1)
+		if children[middle].subId < subId {
+			low = middle + 1
+		} else {
+			high = middle
+		}
+	}
+
+	if low < len(children) && children[low].subId == subId {
+		return &children[low]
+	}
+
+	return nil
+}
+
+func (n *oidTreeNode) insert(oid string, object Object) {
+	parser := oidParser{oid: oid}
+
+	for parser.next() {
+		i, found := slices.BinarySearchFunc(n.children, parser.subId, compareNodeSubId)
+		if !found {
+			n.children = slices.Insert(n.children, i, oidTreeNode{subId: parser.subId, oid: oid[:parser.offset]})
+		}
+
+		n = &n.children[i]
+	}
+
+	if parser.done() {
+		n.object = &object
+	}
+}
+
+// compress merges the nodes without objects that have a single child with the child, for all of the descendants.
+func (n *oidTreeNode) compress() {
+	for i := range n.children {
+		child := &n.children[i]
+
+		for child.object == nil && len(child.children) == 1 {
+			grandchild := child.children[0]
+			child.rest, child.oid = grandchild.oid[len(child.oid)-len(child.rest):], grandchild.oid
+			child.object, child.children = grandchild.object, grandchild.children
+		}
+
+		child.compress()
+	}
+}
+
+// precompute fills in the lookup results for the node and all of its descendants.
+func (n *oidTreeNode) precompute(parent *oidTreeNode) {
+	n.closest, n.displayHint = parent.closest, parent.displayHint
+
+	if n.object != nil {
+		n.closest = n
+
+		// OIDs this short can't point to an OctetString object
+		if len(n.oid) > 7 && n.object.DisplayHint != DisplayHintUnknown {
+			n.displayHint = n.object.DisplayHint
+		}
+	}
+
+	for i := range n.children {
+		n.children[i].precompute(n)
+	}
+}
+
+// find returns the deepest node on the path of the OID (root if there is none) and whether it matches the OID exactly.
+func (t *oidTree) find(oid string) (*oidTreeNode, bool) {
+	node := &t.root
+	parser := oidParser{oid: oid}
+
+	for parser.next() {
+		child := node.child(parser.subId)
+		if child == nil || !parser.skip(child.rest) {
+			return node, false
+		}
+
+		node = child
+	}
+
+	return node, parser.done()
+}
+
+func newOidTree(objects Objects) *oidTree {
+	tree := &oidTree{}
+
+	for oid, object := range objects {
+		tree.root.insert(oid, object)
+	}
+
+	tree.root.compress()
+
+	for i := range tree.root.children {
+		tree.root.children[i].precompute(&tree.root)
+	}
+
+	return tree
+}
+
+// oidParser goes through the sub-identifiers of the OID one by one, without any allocations.
+type oidParser struct {
+	oid    string
+	offset int // end of the current sub-identifier
+	subId  uint32
+	failed bool
+}
+
+// next parses the next sub-identifier. It returns false at the end of the OID, or when the OID turns out to be invalid.
+func (p *oidParser) next() bool {
+	if p.offset == len(p.oid) || p.failed {
+		return false
+	}
+
+	if p.oid[p.offset] != '.' {
+		p.failed = true
+
+		return false
+	}
+
+	var subId uint64
+
+	i := p.offset + 1
+	for ; i < len(p.oid) && p.oid[i] != '.'; i++ {
+		if p.oid[i] < '0' || p.oid[i] > '9' {
+			p.failed = true
+
+			return false
+		}
+
+		if subId = subId*10 + uint64(p.oid[i]-'0'); subId > math.MaxUint32 {
+			p.failed = true
+
+			return false
+		}
+	}
+
+	if i == p.offset+1 {
+		p.failed = true
+
+		return false
+	}
+
+	p.offset, p.subId = i, uint32(subId)
+
+	return true
+}
+
+// skip moves past the given sub-identifiers (with the leading dot) if the OID continues with them.
+func (p *oidParser) skip(subIds string) bool {
+	if subIds == "" {
+		return true
+	}
+
+	if !strings.HasPrefix(p.oid[p.offset:], subIds) {
+		return false
+	}
+
+	if end := p.offset + len(subIds); end != len(p.oid) && p.oid[end] != '.' {
+		return false
+	}
+
+	p.offset += len(subIds)
+
+	return true
+}
+
+// done returns true if the whole (non-empty) OID was parsed successfully.
+func (p *oidParser) done() bool {
+	return !p.failed && p.offset != 0 && p.offset == len(p.oid)
+}
+
+func compareNodeSubId(node oidTreeNode, subId uint32) int {
+	switch {
+	case node.subId < subId:
+		return -1
+	case node.subId > subId:
+		return 1
+	default:
+		return 0
+	}
+}
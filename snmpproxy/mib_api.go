@@ -0,0 +1,174 @@
+package snmpproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+)
+
+type MibObject struct {
+	Oid    string `json:"oid"`
+	Suffix string `json:"suffix,omitempty"`
+	mib.Object
+}
+
+type MibChild struct {
+	Oid  string `json:"oid"`
+	Name string `json:"name"`
+}
+
+type MibResponse struct {
+	Error    string     `json:"error,omitempty"`
+	Oid      string     `json:"oid,omitempty"`
+	Name     string     `json:"name,omitempty"`
+	Object   *MibObject `json:"object,omitempty"`
+	Children []MibChild `json:"children,omitempty"`
+}
+
+// MibApi exposes read-only endpoints for looking up the information in the loaded MIBs:
+//   - GET /mib/translate?oid=.1.3.6.1.2.1.2.2.1.2.5 or ?name=IF-MIB::ifDescr.5 converts between OIDs and names
+//   - GET /mib/object?oid=... or ?name=... shows the details of the MIB object the OID belongs to
+//   - GET /mib/children?oid=... or ?name=... lists the direct children of the node
+type MibApi struct {
+	mibDataProvider atomic.Pointer[mib.DataProvider]
+	mux             *http.ServeMux
+}
+
+func (a *MibApi) SetMibDataProvider(mibDataProvider *mib.DataProvider) {
+	a.mibDataProvider.Store(mibDataProvider)
+}
+
+func (a *MibApi) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	a.mux.ServeHTTP(writer, request)
+}
+
+func (a *MibApi) translate(writer http.ResponseWriter, request *http.Request) {
+	provider := a.mibDataProvider.Load()
+
+	oid, status, err := a.getOid(provider, request)
+	if err != nil {
+		a.writeResponse(writer, status, MibResponse{Error: err.Error()})
+
+		return
+	}
+
+	name, err := provider.Translate(oid)
+	if err != nil {
+		a.writeResponse(writer, http.StatusNotFound, MibResponse{Error: err.Error()})
+
+		return
+	}
+
+	a.writeResponse(writer, http.StatusOK, MibResponse{Oid: oid, Name: name})
+}
+
+func (a *MibApi) object(writer http.ResponseWriter, request *http.Request) {
+	provider := a.mibDataProvider.Load()
+
+	oid, status, err := a.getOid(provider, request)
+	if err != nil {
+		a.writeResponse(writer, status, MibResponse{Error: err.Error()})
+
+		return
+	}
+
+	objectOid, suffix, object, ok := provider.GetObject(oid)
+	if !ok {
+		a.writeResponse(writer, http.StatusNotFound, MibResponse{Error: "unknown OID: " + oid})
+
+		return
+	}
+
+	a.writeResponse(
+		writer,
+		http.StatusOK,
+		MibResponse{Oid: oid, Object: &MibObject{Oid: objectOid, Suffix: suffix, Object: object}},
+	)
+}
+
+func (a *MibApi) children(writer http.ResponseWriter, request *http.Request) {
+	provider := a.mibDataProvider.Load()
+
+	oid, status, err := a.getOid(provider, request)
+	if err != nil {
+		a.writeResponse(writer, status, MibResponse{Error: err.Error()})
+
+		return
+	}
+
+	if _, suffix, _, ok := provider.GetObject(oid); !ok || suffix != "" {
+		a.writeResponse(writer, http.StatusNotFound, MibResponse{Error: "unknown OID: " + oid})
+
+		return
+	}
+
+	childOids := provider.GetChildren(oid)
+	children := make([]MibChild, 0, len(childOids))
+
+	for _, childOid := range childOids {
+		_, _, object, _ := provider.GetObject(childOid)
+		children = append(children, MibChild{Oid: childOid, Name: object.FullName()})
+	}
+
+	a.writeResponse(writer, http.StatusOK, MibResponse{Oid: oid, Children: children})
+}
+
+// getOid returns the OID requested by either the oid or the name query parameter. In case of an error,
+// it also returns the HTTP status code to respond with.
+func (*MibApi) getOid(provider *mib.DataProvider, request *http.Request) (string, int, error) {
+	query := request.URL.Query()
+
+	oid, name := query.Get("oid"), query.Get("name")
+
+	switch {
+	case oid != "" && name != "":
+		return "", http.StatusBadRequest, errors.New("only one of the query parameters oid and name may be provided")
+	case oid != "":
+		if err := validateOid(oid); err != nil {
+			return "", http.StatusBadRequest, fmt.Errorf("OID %w, got: %s", err, oid)
+		}
+
+		return oid, 0, nil
+	case name != "":
+		oid, err := provider.Resolve(name)
+		if err != nil {
+			return "", http.StatusNotFound, err
+		}
+
+		return oid, 0, nil
+	default:
+		return "", http.StatusBadRequest, errors.New("query parameter oid or name must be provided")
+	}
+}
+
+func (*MibApi) writeResponse(writer http.ResponseWriter, status int, response MibResponse) {
+	b, err := json.Marshal(response)
+	if err != nil {
+		panic(err)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_, _ = writer.Write(b)
+}
+
+func NewMibApi(mibDataProvider *mib.DataProvider) *MibApi {
+	api := &MibApi{mux: http.NewServeMux()}
+	api.SetMibDataProvider(mibDataProvider)
+
+	api.mux.HandleFunc("/mib/translate", api.translate)
+	api.mux.HandleFunc("/mib/object", api.object)
+	api.mux.HandleFunc("/mib/children", api.children)
+
+	return api
+}
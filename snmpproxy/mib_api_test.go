@@ -0,0 +1,157 @@
+package snmpproxy_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMibApi(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "translate OID",
+			url:            "/mib/translate?oid=.1.3.6.1.2.1.2.2.1.2.5",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"oid":".1.3.6.1.2.1.2.2.1.2.5","name":"IF-MIB::ifDescr.5"}`,
+		},
+		{
+			name:           "translate name",
+			url:            "/mib/translate?name=IF-MIB::ifDescr.5",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"oid":".1.3.6.1.2.1.2.2.1.2.5","name":"IF-MIB::ifDescr.5"}`,
+		},
+		{
+			name:           "translate unknown OID",
+			url:            "/mib/translate?oid=.2.1",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"unknown OID: .2.1"}`,
+		},
+		{
+			name:           "translate unknown name",
+			url:            "/mib/translate?name=ifWhatever",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"unknown name: ifWhatever"}`,
+		},
+		{
+			name:           "object",
+			url:            "/mib/object?name=ifOperStatus.3",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"oid":".1.3.6.1.2.1.2.2.1.8.3","object":{"oid":".1.3.6.1.2.1.2.2.1.8","suffix":".3",` +
+				`"name":"ifOperStatus","module":"IF-MIB","type":"INTEGER","enums":{"1":"up","2":"down"},` +
+				`"access":"read-only","description":"The current operational state."}}`,
+		},
+		{
+			name:           "object with unknown OID",
+			url:            "/mib/object?oid=.2",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"unknown OID: .2"}`,
+		},
+		{
+			name:           "children",
+			url:            "/mib/children?oid=.1.3.6.1.2.1.2.2.1",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"oid":".1.3.6.1.2.1.2.2.1","children":[` +
+				`{"oid":".1.3.6.1.2.1.2.2.1.2","name":"IF-MIB::ifDescr"},` +
+				`{"oid":".1.3.6.1.2.1.2.2.1.8","name":"IF-MIB::ifOperStatus"}]}`,
+		},
+		{
+			name:           "children of an instance",
+			url:            "/mib/children?oid=.1.3.6.1.2.1.2.2.1.2.5",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"unknown OID: .1.3.6.1.2.1.2.2.1.2.5"}`,
+		},
+		{
+			name:           "missing parameters",
+			url:            "/mib/translate",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"query parameter oid or name must be provided"}`,
+		},
+		{
+			name:           "both parameters",
+			url:            "/mib/translate?oid=.1.3&name=ifDescr",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"only one of the query parameters oid and name may be provided"}`,
+		},
+		{
+			name:           "OID without a dot",
+			url:            "/mib/object?oid=1.3",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"OID must begin with a dot, got: 1.3"}`,
+		},
+		{
+			name:           "OID with a non-numeric sub-identifier",
+			url:            "/mib/translate?oid=.1.3.6foo",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"OID must consist of numbers separated by dots, got: .1.3.6foo"}`,
+		},
+		{
+			name:           "OID with an empty sub-identifier",
+			url:            "/mib/object?oid=..1",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"OID must consist of numbers separated by dots, got: ..1"}`,
+		},
+		{
+			name:           "unknown endpoint",
+			url:            "/mib/whatever",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   "404 page not found\n",
+		},
+		{
+			name:           "not GET",
+			method:         "POST",
+			url:            "/mib/translate?oid=.1.3",
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			api := snmpproxy.NewMibApi(mib.NewDataProvider(nil))
+			api.SetMibDataProvider(
+				mib.NewDataProvider(
+					mib.Objects{
+						".1":                   {Name: "iso", Module: "SNMPv2-SMI"},
+						".1.3.6.1.2.1.2.2.1":   {Name: "ifEntry", Module: "IF-MIB"},
+						".1.3.6.1.2.1.2.2.1.2": {Name: "ifDescr", Module: "IF-MIB"},
+						".1.3.6.1.2.1.2.2.1.8": {
+							Name:        "ifOperStatus",
+							Module:      "IF-MIB",
+							Type:        "INTEGER",
+							Enums:       map[int]string{1: "up", 2: "down"},
+							Access:      "read-only",
+							Description: "The current operational state.",
+						},
+					},
+				),
+			)
+
+			method := test.method
+			if method == "" {
+				method = "GET"
+			}
+
+			recorder := httptest.NewRecorder()
+			api.ServeHTTP(recorder, httptest.NewRequest(method, test.url, nil))
+
+			response := recorder.Result()
+			assert.Equal(test.expectedStatus, response.StatusCode)
+			assert.Equal(test.expectedBody, read(response.Body))
+
+			if strings.HasPrefix(test.expectedBody, "{") {
+				assert.Equal("application/json", response.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
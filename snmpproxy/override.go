@@ -0,0 +1,106 @@
+package snmpproxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OverrideFormat string
+
+const (
+	OverrideFormatString   = OverrideFormat("string")
+	OverrideFormatHex      = OverrideFormat("hex")
+	OverrideFormatMac      = OverrideFormat("mac")
+	OverrideFormatIpv4     = OverrideFormat("ipv4")
+	OverrideFormatDateTime = OverrideFormat("datetime")
+	OverrideFormatUtf16    = OverrideFormat("utf16")
+	OverrideFormatInteger  = OverrideFormat("integer")
+)
+
+// FormatOverride forces the given format for all OIDs under the Prefix, regardless of what the MIBs say.
+type FormatOverride struct {
+	Prefix string
+	Format OverrideFormat
+	Scale  float64 // only used with OverrideFormatInteger, the value is multiplied by it (unless it's zero)
+}
+
+// FormatOverrides holds the global overrides and the overrides specific for device profiles. Overrides of the profile
+// take precedence over the global ones, and the longest matching prefix wins.
+type FormatOverrides struct {
+	global   map[string]FormatOverride
+	profiles map[string]map[string]FormatOverride
+}
+
+func (o *FormatOverrides) Get(profile string, oid string) (FormatOverride, bool) {
+	if o == nil {
+		return FormatOverride{}, false
+	}
+
+	if override, ok := o.find(o.profiles[strings.ToLower(profile)], oid); ok {
+		return override, true
+	}
+
+	return o.find(o.global, oid)
+}
+
+func (*FormatOverrides) find(overrides map[string]FormatOverride, oid string) (FormatOverride, bool) {
+	if len(overrides) == 0 {
+		return FormatOverride{}, false
+	}
+
+	for length := len(oid); length > 0; length = strings.LastIndex(oid[:length], ".") {
+		if override, ok := overrides[oid[:length]]; ok {
+			return override, true
+		}
+	}
+
+	return FormatOverride{}, false
+}
+
+func NewFormatOverrides(global []FormatOverride, profiles map[string][]FormatOverride) (*FormatOverrides, error) {
+	overrides := &FormatOverrides{profiles: make(map[string]map[string]FormatOverride, len(profiles))}
+
+	var err error
+
+	if overrides.global, err = newFormatOverridesMap(global); err != nil {
+		return nil, err
+	}
+
+	for profile, profileOverrides := range profiles {
+		// profile names are case-insensitive (the configuration keys are lower-cased anyway)
+		if overrides.profiles[strings.ToLower(profile)], err = newFormatOverridesMap(profileOverrides); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", profile, err)
+		}
+	}
+
+	return overrides, nil
+}
+
+func newFormatOverridesMap(overrides []FormatOverride) (map[string]FormatOverride, error) {
+	result := make(map[string]FormatOverride, len(overrides))
+
+	for _, override := range overrides {
+		if !strings.HasPrefix(override.Prefix, ".") {
+			return nil, fmt.Errorf("override prefix must begin with a dot, got: %s", override.Prefix)
+		}
+
+		switch override.Format {
+		case OverrideFormatString, OverrideFormatHex, OverrideFormatMac, OverrideFormatIpv4, OverrideFormatDateTime,
+			OverrideFormatUtf16, OverrideFormatInteger:
+		default:
+			return nil, fmt.Errorf("unknown override format \"%s\" for prefix %s", override.Format, override.Prefix)
+		}
+
+		if override.Scale != 0 && override.Format != OverrideFormatInteger {
+			return nil, fmt.Errorf("scale can only be used with the integer format, prefix %s", override.Prefix)
+		}
+
+		if _, ok := result[override.Prefix]; ok {
+			return nil, fmt.Errorf("duplicate override for prefix %s", override.Prefix)
+		}
+
+		result[override.Prefix] = override
+	}
+
+	return result, nil
+}
@@ -0,0 +1,94 @@
+package snmpproxy_test
+
+import (
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOverridesGet(t *testing.T) {
+	overrides, err := snmpproxy.NewFormatOverrides(
+		[]snmpproxy.FormatOverride{
+			{Prefix: ".1.3.6.1.2.1.2", Format: snmpproxy.OverrideFormatHex},
+			{Prefix: ".1.3.6.1.2.1.2.2.1.6", Format: snmpproxy.OverrideFormatMac},
+		},
+		map[string][]snmpproxy.FormatOverride{
+			"Vendor": {{Prefix: ".1.3.6.1.2.1.2.2", Format: snmpproxy.OverrideFormatString}},
+		},
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		profile  string
+		oid      string
+		expected snmpproxy.OverrideFormat
+	}{
+		{name: "no match", oid: ".1.3.6.1.2.1.1.1.0"},
+		{name: "prefix must match whole sub-identifiers", oid: ".1.3.6.1.2.1.22.1"},
+		{name: "global", oid: ".1.3.6.1.2.1.2.2.1.2.1", expected: snmpproxy.OverrideFormatHex},
+		{name: "longest prefix wins", oid: ".1.3.6.1.2.1.2.2.1.6.1", expected: snmpproxy.OverrideFormatMac},
+		{
+			name:     "profile takes precedence",
+			profile:  "vendor",
+			oid:      ".1.3.6.1.2.1.2.2.1.6.1",
+			expected: snmpproxy.OverrideFormatString,
+		},
+		{name: "profile falls back to global", profile: "vendor", oid: ".1.3.6.1.2.1.2.1.0", expected: "hex"},
+		{name: "unknown profile", profile: "unknown", oid: ".1.3.6.1.2.1.2.1.0", expected: "hex"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			override, ok := overrides.Get(test.profile, test.oid)
+			require.Equal(t, test.expected != "", ok)
+			require.Equal(t, test.expected, override.Format)
+		})
+	}
+}
+
+func TestNewFormatOverridesError(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides []snmpproxy.FormatOverride
+		err       string
+	}{
+		{
+			name:      "prefix without dot",
+			overrides: []snmpproxy.FormatOverride{{Prefix: "1.3", Format: snmpproxy.OverrideFormatHex}},
+			err:       "override prefix must begin with a dot, got: 1.3",
+		},
+		{
+			name:      "unknown format",
+			overrides: []snmpproxy.FormatOverride{{Prefix: ".1.3", Format: "whatever"}},
+			err:       `unknown override format "whatever" for prefix .1.3`,
+		},
+		{
+			name:      "scale without integer",
+			overrides: []snmpproxy.FormatOverride{{Prefix: ".1.3", Format: snmpproxy.OverrideFormatHex, Scale: 2}},
+			err:       "scale can only be used with the integer format, prefix .1.3",
+		},
+		{
+			name: "duplicate",
+			overrides: []snmpproxy.FormatOverride{
+				{Prefix: ".1.3", Format: snmpproxy.OverrideFormatHex},
+				{Prefix: ".1.3", Format: snmpproxy.OverrideFormatMac},
+			},
+			err: "duplicate override for prefix .1.3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			overrides, err := snmpproxy.NewFormatOverrides(test.overrides, nil)
+			require.Nil(t, overrides)
+			require.EqualError(t, err, test.err)
+
+			overrides, err = snmpproxy.NewFormatOverrides(
+				nil,
+				map[string][]snmpproxy.FormatOverride{"vendor": test.overrides},
+			)
+			require.Nil(t, overrides)
+			require.EqualError(t, err, "profile vendor: "+test.err)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package snmpproxy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// maxColumnDepth limits how deep the columns are looked for when there is just a single one (e.g. table -> entry).
+	maxColumnDepth = 2
+	// maxColumns limits the round trips spent by looking for the columns, larger roots are walked as usual.
+	maxColumns = 64
+)
+
+// walkColumn is a sub-tree of the walk root, walked by one of the workers of walkParallel.
+type walkColumn struct {
+	oid      string
+	from     string
+	varbinds []gosnmp.SnmpPDU
+	done     bool
+}
+
+// walkParallel walks the columns (sub-trees) of the root concurrently, using up to request.Parallel connections.
+// The varbinds are passed to the function in the lexicographic order, as if the root was walked at once: the varbinds
+// of a column are buffered until all the previous columns are finished. The buffered varbinds count towards
+// the WalkLimits together with the usage of the walk. It returns false if the root can't be split, and so it must be
+// walked as usual.
+func (r *GosnmpRequester) walkParallel(
+	ctx context.Context,
+	snmp *snmpHandler,
+	execution *execution,
+	requestNo int,
+	from string,
+	usage *walkUsage,
+	fn gosnmp.WalkFunc,
+) (bool, error) {
+	root := execution.apiRequest.Requests[requestNo].Oids[0]
+	if root == "" || root == "." {
+		root = ".1.3.6.1.2.1"
+	}
+
+	oids, err := snmp.columns(root)
+	if err != nil {
+		return true, err
+	}
+
+	columns := make([]*walkColumn, 0, len(oids))
+
+	for _, oid := range oids {
+		switch {
+		case strings.HasPrefix(from, oid+"."):
+			columns = append(columns, &walkColumn{oid: oid, from: from})
+		case compareOids(oid, from) > 0:
+			columns = append(columns, &walkColumn{oid: oid, from: oid})
+		default: // the column was walked already by the previous walk
+		}
+	}
+
+	if len(columns) < 2 {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		delivered  int
+		columnErr  error
+		deliverErr error     // the errors of the function are final, nothing is delivered after them
+		pending    walkUsage // the buffered varbinds
+		queue      = make(chan *walkColumn, len(columns))
+	)
+
+	// buffer keeps the varbind of the column until it can be delivered, unless it would exceed the limits
+	buffer := func(column *walkColumn, dataUnit gosnmp.SnmpPDU) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		pending.varbinds++
+		pending.bytes += pduSize(dataUnit)
+
+		if err := execution.limiter.checkPending(usage, pending); err != nil {
+			return err
+		}
+
+		column.varbinds = append(column.varbinds, dataUnit)
+
+		return nil
+	}
+
+	// deliver passes the varbinds of the finished columns, in order, to the function
+	deliver := func(partial bool) {
+		for ; delivered < len(columns) && deliverErr == nil; delivered++ {
+			column := columns[delivered]
+			if !column.done && !partial {
+				return
+			}
+
+			for _, dataUnit := range column.varbinds {
+				pending.varbinds--
+				pending.bytes -= pduSize(dataUnit)
+
+				if deliverErr = fn(dataUnit); deliverErr != nil {
+					cancel()
+
+					return
+				}
+			}
+
+			column.varbinds = nil
+
+			if !column.done {
+				return
+			}
+		}
+	}
+
+	for _, column := range columns {
+		queue <- column
+	}
+
+	close(queue)
+
+	// the handler of the walk is used by the first worker, so that the walk doesn't hold an idle admission slot
+	// (see AdmissionLimits) while its workers wait for theirs
+	defer func(previous context.Context) { snmp.Context = previous }(snmp.Context)
+
+	snmp.Context = ctx
+
+	for worker := range min(int(execution.apiRequest.Requests[requestNo].Parallel), len(columns)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var handler *snmpHandler
+			if worker == 0 {
+				handler = snmp
+			}
+
+			if err := r.walkColumns(ctx, handler, execution, requestNo, queue, &mu, buffer, deliver); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+
+				if columnErr == nil {
+					columnErr = err
+
+					cancel()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if deliverErr != nil {
+		return true, deliverErr
+	}
+
+	if columnErr != nil {
+		// keep what was received in order, e.g. for the partial results
+		if deliver(true); deliverErr != nil {
+			return true, deliverErr
+		}
+
+		return true, columnErr
+	}
+
+	return true, nil
+}
+
+// walkColumns walks the columns from the queue until it's empty. Every worker has its own handler, as the handler
+// can't be used concurrently. If the handler isn't given, it's created (and closed) by the worker.
+func (r *GosnmpRequester) walkColumns(
+	ctx context.Context,
+	snmp *snmpHandler,
+	execution *execution,
+	requestNo int,
+	queue <-chan *walkColumn,
+	mu *sync.Mutex,
+	buffer func(column *walkColumn, dataUnit gosnmp.SnmpPDU) error,
+	deliver func(partial bool),
+) error {
+	if snmp == nil {
+		var err error
+
+		if snmp, err = r.createSnmpHandler(ctx, execution.apiRequest); err != nil {
+			return err
+		}
+
+		defer snmp.Close()
+	}
+
+	for column := range queue {
+		err := r.walk(ctx, snmp, execution, requestNo, func(settings walkSettings) error {
+			// continue right after the last received varbind, so that nothing is collected twice
+			from := column.from
+			if len(column.varbinds) != 0 {
+				from = column.varbinds[len(column.varbinds)-1].Name
+			}
+
+			return snmp.walk(column.oid, from, settings, func(dataUnit gosnmp.SnmpPDU) error {
+				// the limits are final, the walk can't be retried
+				if err := buffer(column, dataUnit); err != nil {
+					return &walkFuncError{err}
+				}
+
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		column.done = true
+		deliver(false)
+		mu.Unlock()
+	}
+
+	return nil
+}
+
+// columns returns the sub-trees right below the root that aren't empty. If there is just a single one (e.g. the entry
+// of a table), its sub-trees are returned instead.
+func (h *snmpHandler) columns(root string) ([]string, error) {
+	for depth := 1; ; depth++ {
+		columns, err := h.children(root)
+		if err != nil || len(columns) != 1 || depth == maxColumnDepth {
+			return columns, err
+		}
+
+		root = columns[0]
+	}
+}
+
+// children finds the non-empty sub-trees right below the root by GetNext of their following siblings. That would skip
+// a sibling which is an instance by itself, so the root isn't split at all if there are instances right below it
+// (e.g. it's a column already). It isn't split either if there are too many sub-trees.
+func (h *snmpHandler) children(root string) ([]string, error) {
+	var children []string
+
+	for next := root; ; {
+		response, err := h.GetNext([]string{next})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(response.Variables) == 0 || response.Error != gosnmp.NoError {
+			return children, nil
+		}
+
+		dataUnit := response.Variables[0]
+
+		switch dataUnit.Type {
+		case gosnmp.EndOfMibView, gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+			return children, nil
+		}
+
+		if !strings.HasPrefix(dataUnit.Name, root+".") {
+			return children, nil
+		}
+
+		subId, rest, _ := strings.Cut(dataUnit.Name[len(root)+1:], ".")
+		if rest == "" || len(children) == maxColumns {
+			return nil, nil
+		}
+
+		n, err := strconv.ParseUint(subId, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID received: %s", dataUnit.Name)
+		}
+
+		children = append(children, root+"."+subId)
+
+		if n == math.MaxUint32 {
+			return children, nil
+		}
+
+		next = root + "." + strconv.FormatUint(n+1, 10)
+	}
+}
@@ -0,0 +1,39 @@
+package snmpproxy
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PartialResultPolicy decides what is returned to the client when the request deadline expires before all
+// the requests of the ApiRequest are finished.
+type PartialResultPolicy string
+
+const (
+	// PartialResultsNone returns only the error.
+	PartialResultsNone = PartialResultPolicy("none")
+	// PartialResultsCompleted returns the results of the finished requests, unfinished requests have null result.
+	PartialResultsCompleted = PartialResultPolicy("completed")
+)
+
+func NewPartialResultPolicy(policy string) (PartialResultPolicy, error) {
+	switch PartialResultPolicy(policy) {
+	case "":
+		return PartialResultsNone, nil
+	case PartialResultsNone, PartialResultsCompleted:
+		return PartialResultPolicy(policy), nil
+	default:
+		return "", fmt.Errorf(
+			"unknown partial result policy \"%s\", supported are: %s, %s",
+			policy,
+			PartialResultsNone,
+			PartialResultsCompleted,
+		)
+	}
+}
+
+// keepsResults decides whether the results are returned together with the error. They are if some of the failed
+// requests have results too (see ErrWalkIncomplete), or if the deadline expired and the policy keeps the results.
+func (p PartialResultPolicy) keepsResults(err error, partial bool) bool {
+	return partial || errors.Is(err, ErrDeadlineExceeded) && p == PartialResultsCompleted
+}
@@ -0,0 +1,33 @@
+package snmpproxy_test
+
+import (
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPartialResultPolicy(t *testing.T) {
+	tests := []struct {
+		policy   string
+		expected snmpproxy.PartialResultPolicy
+		err      string
+	}{
+		{policy: "", expected: snmpproxy.PartialResultsNone},
+		{policy: "none", expected: snmpproxy.PartialResultsNone},
+		{policy: "completed", expected: snmpproxy.PartialResultsCompleted},
+		{policy: "all", err: `unknown partial result policy "all", supported are: none, completed`},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			policy, err := snmpproxy.NewPartialResultPolicy(test.policy)
+
+			if test.err == "" {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, policy)
+			} else {
+				require.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package snmpproxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// defaultPoolIdleTimeout is used when ConnectionPoolSettings.IdleTimeout isn't set.
+const defaultPoolIdleTimeout = time.Minute
+
+// ConnectionPoolSettings configure the reuse of the connected SNMP handlers (UDP sockets), see connectionPool.
+type ConnectionPoolSettings struct {
+	MaxIdle     uint          // idle connections kept per target and credentials, zero disables the pool
+	IdleTimeout time.Duration // idle connections are closed after this long, see defaultPoolIdleTimeout
+}
+
+// poolKey identifies the connections that may be reused for each other, the rest of the settings (e.g. the timeout)
+// is set every time the connection is taken from the pool.
+type poolKey struct {
+	target    string
+	port      uint16
+	version   gosnmp.SnmpVersion
+	community string
+}
+
+type idleConnection struct {
+	snmp  *gosnmp.GoSNMP
+	since time.Time
+}
+
+// connectionPool keeps the connections that aren't used anymore, so that the following requests for the same target
+// with the same credentials don't have to open new sockets. It doesn't multiplex the requests: a connection is only
+// ever used by a single handler at once, so the concurrent requests for the same target still use a socket each.
+// The request IDs only tell apart the late responses to the previous requests of the connection (e.g. those that
+// timed out), which gosnmp drops.
+type connectionPool struct {
+	settings ConnectionPoolSettings
+	mu       sync.Mutex
+	idle     map[poolKey][]idleConnection // the most recently used connections are last
+	evicting bool                         // whether the eviction of the idle connections is scheduled
+	idleSize prometheus.Gauge
+	taken    *prometheus.CounterVec
+}
+
+// get returns an idle connection, or nil if there is none.
+func (p *connectionPool) get(key poolKey) *gosnmp.GoSNMP {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	connections := p.idle[key]
+	if len(connections) == 0 {
+		p.taken.WithLabelValues("created").Inc()
+
+		return nil
+	}
+
+	snmp := connections[len(connections)-1].snmp
+	p.setIdle(key, connections[:len(connections)-1])
+	p.taken.WithLabelValues("reused").Inc()
+
+	return snmp
+}
+
+// put returns the connection to the pool, or closes it if there are too many idle connections already.
+func (p *connectionPool) put(key poolKey, snmp *gosnmp.GoSNMP) {
+	// don't keep the context of the finished request
+	snmp.Context = nil
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	connections := p.idle[key]
+	if len(connections) >= int(p.settings.MaxIdle) {
+		_ = snmp.Conn.Close()
+
+		return
+	}
+
+	p.setIdle(key, append(connections, idleConnection{snmp: snmp, since: time.Now()}))
+
+	if !p.evicting {
+		p.evicting = true
+
+		time.AfterFunc(p.settings.IdleTimeout, p.evict)
+	}
+}
+
+// evict closes the connections that are idle for too long. It's scheduled for as long as there are any idle ones.
+func (p *connectionPool) evict() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+
+	for key, connections := range p.idle {
+		expired := 0
+		for expired < len(connections) && now.Sub(connections[expired].since) >= p.settings.IdleTimeout {
+			_ = connections[expired].snmp.Conn.Close()
+			expired++
+		}
+
+		p.setIdle(key, connections[expired:])
+	}
+
+	if p.evicting = len(p.idle) != 0; p.evicting {
+		time.AfterFunc(p.settings.IdleTimeout/2, p.evict)
+	}
+}
+
+func (p *connectionPool) setIdle(key poolKey, connections []idleConnection) {
+	p.idleSize.Add(float64(len(connections) - len(p.idle[key])))
+
+	if len(connections) == 0 {
+		delete(p.idle, key)
+	} else {
+		p.idle[key] = connections
+	}
+}
+
+func newConnectionPool(settings ConnectionPoolSettings) *connectionPool {
+	return &connectionPool{
+		settings: settings,
+		idle:     make(map[poolKey][]idleConnection),
+		idleSize: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "snmpproxy",
+			Name:      "pool_idle_connections",
+			Help:      "Number of the idle SNMP connections kept for reuse",
+		}),
+		taken: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "snmpproxy",
+			Name:      "pool_connections_total",
+			Help:      "Number of the SNMP connections used by the requests, by whether they were created or reused",
+		}, []string{"origin"}),
+	}
+}
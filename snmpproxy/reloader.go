@@ -0,0 +1,80 @@
+package snmpproxy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+	"go.uber.org/zap"
+)
+
+var ErrMibReloadInProgress = errors.New("MIB reload is already in progress")
+
+type MibDataProviderSetter interface {
+	SetMibDataProvider(mibDataProvider *mib.DataProvider)
+}
+
+// MibReloader parses the MIBs and atomically swaps the resulting mib.DataProvider into all of its consumers.
+// If the parsing fails, the consumers keep using the previous MIB data.
+type MibReloader struct {
+	parser    mib.Parser
+	consumers []MibDataProviderSetter
+	logger    *zap.SugaredLogger
+	mu        sync.Mutex
+}
+
+func (r *MibReloader) Reload() error {
+	if !r.mu.TryLock() {
+		return ErrMibReloadInProgress
+	}
+	defer r.mu.Unlock()
+
+	r.logger.Info("reloading MIBs")
+
+	objects, err := r.parser.Parse()
+	if err != nil {
+		r.logger.Errorw("failed to reload MIBs, keeping the previous ones", zap.Error(err))
+
+		return err
+	}
+
+	mibDataProvider := mib.NewDataProvider(objects)
+
+	for _, consumer := range r.consumers {
+		consumer.SetMibDataProvider(mibDataProvider)
+	}
+
+	r.logger.Infow("MIBs reloaded", "objects", len(objects))
+
+	return nil
+}
+
+func (r *MibReloader) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "POST" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	response := Response{}
+
+	switch err := r.Reload(); {
+	case err == nil:
+		writer.WriteHeader(http.StatusOK)
+	case errors.Is(err, ErrMibReloadInProgress):
+		writer.WriteHeader(http.StatusConflict)
+
+		response.Error = err.Error()
+	default:
+		writer.WriteHeader(http.StatusInternalServerError)
+
+		response.Error = err.Error()
+	}
+
+	_, _ = writer.Write(response.Bytes())
+}
+
+func NewMibReloader(parser mib.Parser, logger *zap.SugaredLogger, consumers ...MibDataProviderSetter) *MibReloader {
+	return &MibReloader{parser: parser, consumers: consumers, logger: logger}
+}
@@ -0,0 +1,126 @@
+package snmpproxy_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type mockMibParser struct {
+	mock.Mock
+}
+
+func (p *mockMibParser) Parse() (mib.Objects, error) {
+	args := p.Mock.Called()
+
+	result := args.Get(0)
+	if result == nil {
+		return nil, args.Error(1)
+	}
+
+	return result.(mib.Objects), args.Error(1)
+}
+
+var hexPdu = gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2.1", Type: gosnmp.OctetString, Value: []byte{'a', 'b'}}
+
+func TestMibReloaderReload(t *testing.T) {
+	assert := require.New(t)
+
+	parser := &mockMibParser{}
+	defer parser.AssertExpectations(t)
+
+	parser.On("Parse").Once().Return(
+		mib.Objects{".1.3.6.1.2.1.2.2.1.2": {Name: "ifDescr", DisplayHint: mib.DisplayHintHexadecimal}},
+		nil,
+	)
+	parser.On("Parse").Once().Return(nil, errors.New("broken MIB"))
+
+	formatter := snmpproxy.NewValueFormatter(mib.NewDataProvider(nil))
+	reloader := snmpproxy.NewMibReloader(parser, zap.NewNop().Sugar(), formatter)
+
+	assert.Equal("ab", formatter.Format(hexPdu))
+
+	assert.NoError(reloader.Reload())
+	assert.Equal("61 62", formatter.Format(hexPdu))
+
+	assert.EqualError(reloader.Reload(), "broken MIB")
+	assert.Equal("61 62", formatter.Format(hexPdu), "previous MIB data must be kept")
+}
+
+func TestMibReloaderReloadInProgress(t *testing.T) {
+	assert := require.New(t)
+
+	parsing := make(chan struct{})
+	finish := make(chan struct{})
+
+	parser := &mockMibParser{}
+	defer parser.AssertExpectations(t)
+
+	parser.On("Parse").Once().Run(func(mock.Arguments) {
+		close(parsing)
+		<-finish
+	}).Return(mib.Objects{}, nil)
+
+	reloader := snmpproxy.NewMibReloader(parser, zap.NewNop().Sugar())
+
+	errChan := make(chan error)
+
+	go func() {
+		errChan <- reloader.Reload()
+	}()
+
+	<-parsing
+	assert.ErrorIs(reloader.Reload(), snmpproxy.ErrMibReloadInProgress)
+
+	close(finish)
+	assert.NoError(<-errChan)
+}
+
+func TestMibReloaderServeHTTP(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		parseErr       error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "success", method: "POST", expectedStatus: http.StatusOK, expectedBody: `{}`},
+		{
+			name:           "parse error",
+			method:         "POST",
+			parseErr:       errors.New("broken MIB"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"error":"broken MIB"}`,
+		},
+		{name: "not POST", method: "GET", expectedStatus: http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			parser := &mockMibParser{}
+			defer parser.AssertExpectations(t)
+
+			if test.method == "POST" {
+				parser.On("Parse").Once().Return(mib.Objects{}, test.parseErr)
+			}
+
+			reloader := snmpproxy.NewMibReloader(parser, zap.NewNop().Sugar())
+
+			recorder := httptest.NewRecorder()
+			reloader.ServeHTTP(recorder, httptest.NewRequest(test.method, "/admin/mib/reload", nil))
+
+			response := recorder.Result()
+			assert.Equal(test.expectedStatus, response.StatusCode)
+			assert.Equal(test.expectedBody, read(response.Body))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package snmpproxy
+
+import (
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	// initialAutoMaxRepetitions is used for the hosts with no learned max repetitions yet.
+	initialAutoMaxRepetitions = 20
+	// maxAutoMaxRepetitions caps the learned max repetitions, larger responses hardly save anything.
+	maxAutoMaxRepetitions = 200
+)
+
+// repetitionsTuner learns the max repetitions of the walks of a host that use max_repetitions "auto". It lowers them
+// when the agent fails, responds slowly or complains that the response would be too big, and it follows the agents
+// that truncate the responses. While the agent responds quickly, it slowly raises them.
+type repetitionsTuner struct {
+	memory  *WalkMemory
+	host    string
+	timeout time.Duration
+}
+
+// maxRepetitions returns the max repetitions to start the walk with.
+func (t *repetitionsTuner) maxRepetitions() uint32 {
+	learned, ok := t.memory.Get(t.host)
+	if !ok || learned.AutoMaxRepetitions == 0 {
+		return initialAutoMaxRepetitions
+	}
+
+	return learned.AutoMaxRepetitions
+}
+
+// observe learns from the response to GetBulk with the requested max repetitions, and returns the max repetitions
+// to use for the next request. The response is nil if the request failed. The response is truncated if it has fewer
+// varbinds than requested, and yet the walk isn't finished.
+func (t *repetitionsTuner) observe(
+	requested uint32,
+	response *gosnmp.SnmpPacket,
+	truncated bool,
+	latency time.Duration,
+) uint32 {
+	next := requested
+
+	switch {
+	case response == nil, response.Error == gosnmp.TooBig:
+		next = requested / 2
+	case latency > t.timeout/2:
+		next = requested * 3 / 4
+	case truncated:
+		next = uint32(len(response.Variables))
+	case latency < t.timeout/4 && len(response.Variables) >= int(requested):
+		next = requested + requested/4 + 1
+	}
+
+	next = max(1, min(next, maxAutoMaxRepetitions))
+
+	t.memory.update(t.host, func(learned *LearnedWalkSettings) {
+		learned.AutoMaxRepetitions = next
+	})
+
+	return next
+}
@@ -1,12 +1,15 @@
 package snmpproxy
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
 	"net/netip"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gosnmp/gosnmp"
 )
@@ -14,100 +17,414 @@ import (
 type requestResult struct {
 	requestNo int
 	result    []any
+	cursor    *WalkCursor
 	error     error
 }
 
+// Result holds the results of the requests of an ApiRequest, in the same order as the requests.
+type Result struct {
+	Varbinds [][]any
+	// Cursors allow to continue the walks that were stopped early (e.g. by max_results), other requests have nil.
+	// It's nil if there is no such walk.
+	Cursors []*WalkCursor
+	// Cached holds the time the results served from the cache were received, fresh results have zero time. It's nil
+	// if the results don't go through the cache, see CachingRequester.
+	Cached []time.Time
+}
+
+// VarbindFunc receives the varbinds of the requests as soon as they arrive. It may be called from multiple goroutines
+// at once. Returning an error aborts the whole ApiRequest.
+type VarbindFunc func(requestNo int, oid string, value any) error
+
 type Requester interface {
-	ExecuteRequest(apiRequest *ApiRequest) ([][]any, error)
+	// ExecuteRequest executes all the requests of the ApiRequest. Cancelling the context aborts all the outstanding
+	// SNMP operations.
+	ExecuteRequest(ctx context.Context, apiRequest *ApiRequest) (*Result, error)
+	// StreamRequest is like ExecuteRequest, but instead of collecting the results, it passes them to the function.
+	// Only the cursors are returned (see Result).
+	StreamRequest(ctx context.Context, apiRequest *ApiRequest, fn VarbindFunc) ([]*WalkCursor, error)
+}
+
+type snmpHandler struct {
+	*gosnmp.GoSNMP
+	stopClosing func() bool
+	release     func() // releases the admission slot of the handler, see AdmissionLimits
+	limiter     *targetLimiter
+	breaker     *CircuitBreaker
+	pool        *connectionPool // optional, the connection is returned to it by Close
+	poolKey     poolKey
+	retryPolicy RetryPolicy
+	timeout     time.Duration // of the first attempt, see RetryPolicy
+	retries     int
+	latency     time.Duration // of the last attempt of the last request, excluding the wait for the target limiter
+}
+
+func (h *snmpHandler) Get(oids []string) (*gosnmp.SnmpPacket, error) {
+	return h.send(func() (*gosnmp.SnmpPacket, error) {
+		return h.GoSNMP.Get(oids)
+	})
+}
+
+func (h *snmpHandler) GetNext(oids []string) (*gosnmp.SnmpPacket, error) {
+	return h.send(func() (*gosnmp.SnmpPacket, error) {
+		return h.GoSNMP.GetNext(oids)
+	})
+}
+
+func (h *snmpHandler) GetBulk(oids []string, nonRepeaters uint8, maxRepetitions uint32) (*gosnmp.SnmpPacket, error) {
+	return h.send(func() (*gosnmp.SnmpPacket, error) {
+		return h.GoSNMP.GetBulk(oids, nonRepeaters, maxRepetitions)
+	})
+}
+
+// send sends the request unless the circuit of the target is open, once there is a free slot of the target.
+func (h *snmpHandler) send(send func() (*gosnmp.SnmpPacket, error)) (*gosnmp.SnmpPacket, error) {
+	done, err := h.breaker.allow(h.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := h.limiter.acquire(h.Context, h.Target)
+	if err != nil {
+		done(outcomeUnknown)
+
+		return nil, err
+	}
+
+	defer release()
+
+	var packet *gosnmp.SnmpPacket
+
+	// the retries are sent here rather than by gosnmp, so that their timeouts follow the retry policy
+	for attempt := 0; ; attempt++ {
+		h.Timeout = h.retryPolicy.timeout(h.timeout, attempt)
+
+		start := time.Now()
+		packet, err = send()
+		h.latency = time.Since(start)
+
+		if attempt == h.retries || !h.isTimeout(err) {
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+		done(outcomeResponse)
+	case h.isTimeout(err):
+		done(outcomeTimeout)
+	default:
+		done(outcomeUnknown)
+	}
+
+	return packet, err
+}
+
+// isTimeout returns whether the error is a timeout of the request. The cancelled requests aren't.
+func (h *snmpHandler) isTimeout(err error) bool {
+	return err != nil && h.Context.Err() == nil && !errors.Is(err, context.DeadlineExceeded) &&
+		strings.Contains(err.Error(), "timeout")
+}
+
+func (h *snmpHandler) Close() {
+	// if the context is already done, the connection is being closed already
+	if !h.stopClosing() {
+		return
+	}
+
+	if h.pool != nil {
+		h.pool.put(h.poolKey, h.GoSNMP)
+		h.release()
+	} else {
+		h.closeConn()
+	}
+}
+
+func (h *snmpHandler) closeConn() {
+	_ = h.Conn.Close()
+	h.release()
+}
+
+func (h *snmpHandler) getter(requestType RequestType) func(oids []string) (*gosnmp.SnmpPacket, error) {
+	if requestType == Get {
+		return h.Get
+	}
+
+	return h.GetNext
+}
+
+// execution holds the state shared by all the requests of a single ApiRequest.
+type execution struct {
+	apiRequest *ApiRequest
+	results    chan requestResult
+	fn         VarbindFunc // optional, see StreamRequest
+	limiter    *walkLimiter
+	formatter  *formatSnapshot
 }
 
 type GosnmpRequester struct {
-	valueFormatter *ValueFormatter
+	valueFormatter      *ValueFormatter
+	partialResultPolicy PartialResultPolicy
+	walkLimits          WalkLimits
+	walkStrategy        WalkStrategy // used by the walks that don't request any
+	walkMemory          *WalkMemory
+	admission           *admissionController // optional, see AdmissionLimits
+	targetLimiter       *targetLimiter       // optional, see TargetLimits
+	circuitBreaker      *CircuitBreaker      // optional
+	connectionPool      *connectionPool      // optional
+	retryPolicies       RetryPolicies
 }
 
-func (r *GosnmpRequester) ExecuteRequest(apiRequest *ApiRequest) ([][]any, error) {
-	resultsChan := make(chan requestResult)
+func (r *GosnmpRequester) ExecuteRequest(ctx context.Context, apiRequest *ApiRequest) (*Result, error) {
+	return r.execute(ctx, apiRequest, nil)
+}
+
+func (r *GosnmpRequester) StreamRequest(
+	ctx context.Context,
+	apiRequest *ApiRequest,
+	fn VarbindFunc,
+) ([]*WalkCursor, error) {
+	result, err := r.execute(ctx, apiRequest, fn)
+	if result == nil {
+		return nil, err
+	}
+
+	return result.Cursors, err
+}
+
+// ExecuteIndependently is like ExecuteRequest, except that the requests don't fail because of each other. It returns
+// the error of every request instead, the results of the failed requests are kept only if they are partial
+// (see ErrWalkIncomplete).
+func (r *GosnmpRequester) ExecuteIndependently(ctx context.Context, apiRequest *ApiRequest) (*Result, []error) {
+	results, errs, _ := r.executeRequests(ctx, apiRequest, nil, false)
+
+	return results, errs
+}
+
+// PartialResultPolicy returns the policy set by SetPartialResultPolicy.
+func (r *GosnmpRequester) PartialResultPolicy() PartialResultPolicy {
+	return r.partialResultPolicy
+}
+
+// execute executes the requests of the ApiRequest. If the function is given, the varbinds are passed to it
+// instead of being collected in the results.
+func (r *GosnmpRequester) execute(ctx context.Context, apiRequest *ApiRequest, fn VarbindFunc) (*Result, error) {
+	// there is no point in finishing the remaining requests once any of them fails
+	results, errs, err := r.executeRequests(ctx, apiRequest, fn, true)
+	if err == nil {
+		return results, nil
+	}
+
+	partial := slices.ContainsFunc(errs, func(err error) bool { return errors.Is(err, ErrWalkIncomplete) })
+	if r.partialResultPolicy.keepsResults(err, partial) {
+		return results, err
+	}
+
+	return nil, err
+}
+
+// executeRequests executes the requests of the ApiRequest and returns their results, the error of every request
+// and the first error. If failFast is set, the remaining requests are cancelled once any of them fails.
+func (r *GosnmpRequester) executeRequests(
+	ctx context.Context,
+	apiRequest *ApiRequest,
+	fn VarbindFunc,
+	failFast bool,
+) (*Result, []error, error) {
+	if apiRequest.Deadline != 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeoutCause(
+			ctx,
+			apiRequest.Deadline,
+			fmt.Errorf("%w (%s)", ErrDeadlineExceeded, apiRequest.Deadline),
+		)
+		defer cancel()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	execution := &execution{
+		apiRequest: apiRequest,
+		results:    make(chan requestResult),
+		fn:         fn,
+		limiter:    &walkLimiter{limits: r.walkLimits},
+		formatter:  r.valueFormatter.snapshot(apiRequest.Profile),
+	}
+
+	var gets, getNexts []int
 
 	for requestNo, request := range apiRequest.Requests {
 		switch request.RequestType {
-		case Get, GetNext:
-			go r.executeGet(apiRequest, requestNo, resultsChan)
+		case Get:
+			gets = append(gets, requestNo)
+		case GetNext:
+			getNexts = append(getNexts, requestNo)
 		case Walk:
-			go r.executeWalk(apiRequest, requestNo, resultsChan)
+			go r.executeWalk(ctx, execution, requestNo)
+		}
+	}
+
+	for _, requestNos := range [][]int{gets, getNexts} {
+		if len(requestNos) != 0 {
+			go r.executeGets(ctx, execution, requestNos)
 		}
 	}
 
-	errChan := make(chan error)
-	results := make([][]any, len(apiRequest.Requests))
+	var err error
 
-	go func() {
-		var errored bool
+	results := &Result{Varbinds: make([][]any, len(apiRequest.Requests))}
+	errs := make([]error, len(apiRequest.Requests))
 
-		for i := len(apiRequest.Requests); i > 0; i-- {
-			result := <-resultsChan
+	// the cancelled requests finish right away, so it's fine to wait for all of them
+	for i := len(apiRequest.Requests); i > 0; i-- {
+		result := <-execution.results
 
-			if errored {
-				continue
-			}
+		// the incomplete walks have both the error and the cursor to resume them
+		if result.error == nil || errors.Is(result.error, ErrWalkIncomplete) {
+			results.Varbinds[result.requestNo] = result.result
 
-			if result.error != nil {
-				errored = true
-				errChan <- result.error
+			if result.cursor != nil {
+				if results.Cursors == nil {
+					results.Cursors = make([]*WalkCursor, len(apiRequest.Requests))
+				}
 
-				continue
+				results.Cursors[result.requestNo] = result.cursor
 			}
+		}
 
-			results[result.requestNo] = result.result
+		if result.error == nil {
+			continue
 		}
 
-		close(errChan)
-	}()
+		errs[result.requestNo] = result.error
 
-	if err := <-errChan; err != nil {
-		return nil, err
+		if err == nil {
+			err = result.error
+
+			if failFast {
+				cancel()
+			}
+		}
 	}
 
-	return results, nil
+	return results, errs, err
 }
 
-func (r *GosnmpRequester) executeGet(apiRequest *ApiRequest, requestNo int, resultChan chan<- requestResult) {
-	var (
-		err     error
-		request = apiRequest.Requests[requestNo]
-		result  = requestResult{requestNo: requestNo}
-	)
+// SetPartialResultPolicy sets what is returned when the request deadline expires, see PartialResultPolicy.
+func (r *GosnmpRequester) SetPartialResultPolicy(policy PartialResultPolicy) {
+	r.partialResultPolicy = policy
+}
 
-	defer func() {
-		result.error = err
+func (r *GosnmpRequester) SetWalkLimits(limits WalkLimits) {
+	r.walkLimits = limits
+}
 
-		resultChan <- result
-	}()
+// SetTargetLimits limits the PDUs sent to the targets at once, see TargetLimits. It registers the metrics
+// of the limiter, so it may only be called once.
+func (r *GosnmpRequester) SetTargetLimits(limits TargetLimits) {
+	r.targetLimiter = newTargetLimiter(limits)
+}
 
-	snmp, err := r.createSnmpHandler(apiRequest)
-	if err != nil {
-		err = r.maybeConvertErrToTimeout(err, request.Oids)
+// SetAdmissionLimits limits the SNMP operations executed at once, see AdmissionLimits. It registers the metrics
+// of the admission control, so it may only be called once.
+func (r *GosnmpRequester) SetAdmissionLimits(limits AdmissionLimits) {
+	r.admission = newAdmissionController(limits)
+}
 
+// SetCircuitBreaker enables the CircuitBreaker, unless the MaxTimeouts of the settings is zero. It registers
+// the metrics of the breaker, so it may only be called once.
+func (r *GosnmpRequester) SetCircuitBreaker(settings CircuitBreakerSettings) {
+	if settings.MaxTimeouts != 0 {
+		r.circuitBreaker = newCircuitBreaker(settings)
+	}
+}
+
+// SetConnectionPool enables the reuse of the connections, unless the MaxIdle of the settings is zero. It registers
+// the metrics of the pool, so it may only be called once.
+func (r *GosnmpRequester) SetConnectionPool(settings ConnectionPoolSettings) {
+	if settings.MaxIdle == 0 {
 		return
 	}
 
-	var getter func(oids []string) (*gosnmp.SnmpPacket, error)
-	if request.RequestType == Get {
-		getter = snmp.Get
-	} else {
-		getter = snmp.GetNext
+	if settings.IdleTimeout == 0 {
+		settings.IdleTimeout = defaultPoolIdleTimeout
 	}
 
-	packet, err := getter(request.Oids)
+	r.connectionPool = newConnectionPool(settings)
+}
+
+// SetRetryPolicies sets the retry policies of the requests, see RetryPolicies.
+func (r *GosnmpRequester) SetRetryPolicies(policies RetryPolicies) {
+	r.retryPolicies = policies
+}
+
+// CircuitBreaker returns the CircuitBreaker, e.g. to expose the states of the circuits by the admin endpoint.
+// It's nil if the breaker isn't enabled.
+func (r *GosnmpRequester) CircuitBreaker() *CircuitBreaker {
+	return r.circuitBreaker
+}
+
+// WalkMemory returns the settings of the walks learned for the hosts, e.g. to expose them by the admin endpoint.
+func (r *GosnmpRequester) WalkMemory() *WalkMemory {
+	return r.walkMemory
+}
+
+// SetWalkStrategy sets the strategy of the walks that don't specify any, see WalkStrategy.
+func (r *GosnmpRequester) SetWalkStrategy(strategy WalkStrategy) {
+	r.walkStrategy = strategy
+}
+
+// executeGet executes a single get (or getNext) request with the handler.
+func (r *GosnmpRequester) executeGet(
+	ctx context.Context,
+	snmp *snmpHandler,
+	execution *execution,
+	requestNo int,
+) requestResult {
+	request := execution.apiRequest.Requests[requestNo]
+
+	packet, err := snmp.getter(request.RequestType)(request.Oids)
 	if err != nil {
-		err = r.maybeConvertErrToTimeout(err, request.Oids)
+		return requestResult{requestNo: requestNo, error: r.maybeConvertErrToTimeout(ctx, err, request.Oids)}
+	}
 
-		return
+	return r.processGetResult(execution, requestNo, packet)
+}
+
+// processGetResult converts the packet into the result of the get (or getNext) request, or streams it.
+func (r *GosnmpRequester) processGetResult(
+	execution *execution,
+	requestNo int,
+	packet *gosnmp.SnmpPacket,
+) requestResult {
+	var (
+		err    error
+		result = requestResult{requestNo: requestNo}
+	)
+
+	result.result, err = r.processGetPacket(packet, execution.formatter, execution.apiRequest.Requests[requestNo])
+	if err != nil || execution.fn == nil {
+		result.error = err
+
+		return result
+	}
+
+	for i := 0; i < len(result.result) && err == nil; i += 2 {
+		err = execution.fn(requestNo, result.result[i].(string), result.result[i+1])
 	}
 
-	result.result, err = r.processGetPacket(packet, request)
+	result.result, result.error = nil, err
+
+	return result
 }
 
-func (r *GosnmpRequester) processGetPacket(packet *gosnmp.SnmpPacket, request Request) ([]any, error) {
+func (r *GosnmpRequester) processGetPacket(
+	packet *gosnmp.SnmpPacket,
+	formatter *formatSnapshot,
+	request Request,
+) ([]any, error) {
 	if packet.Error == gosnmp.NoSuchName {
 		var oidsString string
 
@@ -139,65 +456,212 @@ func (r *GosnmpRequester) processGetPacket(packet *gosnmp.SnmpPacket, request Re
 			return result, fmt.Errorf("end of mib: %s", dataUnit.Name)
 		}
 
-		result = append(result, dataUnit.Name, r.valueFormatter.Format(dataUnit))
+		result = append(result, dataUnit.Name, formatter.format(dataUnit))
 	}
 
 	return result, nil
 }
 
-func (r *GosnmpRequester) executeWalk(apiRequest *ApiRequest, requestNo int, resultChan chan<- requestResult) {
+func (r *GosnmpRequester) executeWalk(ctx context.Context, execution *execution, requestNo int) {
 	var (
-		err     error
-		request = apiRequest.Requests[requestNo]
-		result  = requestResult{requestNo: requestNo}
+		err        error
+		apiRequest = execution.apiRequest
+		request    = apiRequest.Requests[requestNo]
+		result     = requestResult{requestNo: requestNo}
+		usage      = walkUsage{oid: request.Oids[0]}
 	)
 
 	defer func() {
 		result.error = err
 
-		resultChan <- result
+		execution.results <- result
 	}()
 
-	snmp, err := r.createSnmpHandler(apiRequest)
+	snmp, err := r.createSnmpHandler(ctx, apiRequest)
 	if err != nil {
-		err = r.maybeConvertErrToTimeout(err, request.Oids)
+		err = r.maybeConvertErrToTimeout(ctx, err, request.Oids)
 
 		return
 	}
 
-	snmp.SetMaxRepetitions(request.MaxRepetitions)
+	defer snmp.Close()
 
-	var walker func(string, gosnmp.WalkFunc) error
-	if apiRequest.Version == SnmpVersion(gosnmp.Version1) {
-		walker = snmp.Walk
-	} else {
-		walker = snmp.BulkWalk
+	var lastOid string
+
+	oid, from := request.Oids[0], request.Oids[0]
+	if request.Cursor != nil {
+		from, lastOid = request.Cursor.Oid, request.Cursor.Oid
 	}
 
-	oid := request.Oids[0]
+	walkFn := func(dataUnit gosnmp.SnmpPDU) error {
+		// the limit is checked only once there is another varbind, so that there is no cursor for a finished walk
+		if request.MaxResults != 0 && usage.varbinds == uint64(request.MaxResults) {
+			result.cursor = &WalkCursor{Root: oid, Oid: lastOid}
+
+			return errWalkStopped
+		}
+
+		lastOid = dataUnit.Name
 
-	err = walker(oid, func(dataUnit gosnmp.SnmpPDU) error {
-		result.result = append(result.result, dataUnit.Name, r.valueFormatter.Format(dataUnit))
+		value := execution.formatter.format(dataUnit)
+		if err := execution.limiter.add(&usage, dataUnit.Name, value); err != nil {
+			return err
+		}
+
+		if execution.fn != nil {
+			return execution.fn(requestNo, dataUnit.Name, value)
+		}
+
+		result.result = append(result.result, dataUnit.Name, value)
 
 		return nil
-	})
-	if err != nil {
-		err = r.maybeConvertErrToTimeout(err, []string{oid})
+	}
+
+	var split bool
+
+	if request.Parallel > 1 {
+		split, err = r.walkParallel(ctx, snmp, execution, requestNo, from, &usage, walkFn)
+	}
+
+	if !split {
+		err = r.walk(ctx, snmp, execution, requestNo, func(settings walkSettings) error {
+			// continue right after the last received varbind, so that nothing is returned twice
+			if lastOid != "" {
+				from = lastOid
+			}
+
+			return snmp.walk(oid, from, settings, func(dataUnit gosnmp.SnmpPDU) error {
+				// the walk can't be retried once the function fails, as the failure is final (e.g. a limit)
+				if err := walkFn(dataUnit); err != nil {
+					return &walkFuncError{err}
+				}
+
+				return nil
+			})
+		})
+	}
+
+	var walkFuncErr *walkFuncError
+	if errors.As(err, &walkFuncErr) {
+		err = walkFuncErr.err
+	}
+
+	switch {
+	case errors.Is(err, errWalkStopped):
+		err = nil
+	case err != nil:
+		err = r.maybeConvertErrToTimeout(ctx, err, []string{oid})
+
+		// keep what was received so far, the client may resume the walk with the cursor
+		if request.PartialResults && lastOid != "" &&
+			(errors.Is(err, ErrTimeout) || errors.Is(err, ErrDeadlineExceeded) || errors.Is(err, ErrTargetUnavailable)) {
+			result.cursor = &WalkCursor{Root: oid, Oid: lastOid}
+			err = fmt.Errorf("%w (%w, stopped after %s)", err, ErrWalkIncomplete, lastOid)
+
+			if result.result == nil && execution.fn == nil {
+				result.result = []any{}
+			}
+		}
 
 		return
 	}
 
-	if len(result.result) != 0 {
+	if usage.varbinds != 0 {
 		return
 	}
 
-	err = r.getWalkFailureReason(snmp, oid)
+	// a continued walk may just end right at the cursor
+	if request.Cursor != nil {
+		result.result = []any{}
+
+		return
+	}
+
+	err = r.getWalkFailureReason(ctx, snmp, oid)
 }
 
-func (r *GosnmpRequester) getWalkFailureReason(snmp gosnmp.Handler, oid string) error {
+// walkFuncError wraps the errors returned by the gosnmp.WalkFunc, so that they can be told apart from the SNMP errors.
+type walkFuncError struct {
+	err error
+}
+
+func (e *walkFuncError) Error() string {
+	return e.err.Error()
+}
+
+// walk executes the walk with the settings based on the walk strategy. With WalkStrategyAuto, the failed walk
+// is continued with the fallback settings, and the settings that made it succeed are remembered for the host.
+func (r *GosnmpRequester) walk(
+	ctx context.Context,
+	snmp *snmpHandler,
+	execution *execution,
+	requestNo int,
+	walk func(settings walkSettings) error,
+) error {
+	request := execution.apiRequest.Requests[requestNo]
+
+	strategy := request.WalkStrategy
+	if strategy == "" {
+		strategy = r.walkStrategy
+	}
+
+	host := execution.apiRequest.Host
+
+	settings := walkSettings{
+		getNext:        strategy == WalkStrategyGetNext || snmp.Version == gosnmp.Version1,
+		maxRepetitions: request.MaxRepetitions,
+	}
+
+	if request.AutoMaxRepetitions {
+		settings.tuner = &repetitionsTuner{memory: r.walkMemory, host: host, timeout: execution.apiRequest.Timeout}
+		settings.maxRepetitions = settings.tuner.maxRepetitions()
+	}
+
+	if settings.maxRepetitions == 0 {
+		settings.maxRepetitions = defaultMaxRepetitions
+	}
+
+	if strategy != WalkStrategyAuto {
+		return walk(settings)
+	}
+
+	learned, ok := r.walkMemory.Get(host)
+	if ok {
+		settings.getNext = settings.getNext || learned.GetNext
+
+		if learned.MaxRepetitions != 0 {
+			settings.maxRepetitions = min(settings.maxRepetitions, learned.MaxRepetitions)
+		}
+	}
+
+	for fellBack := false; ; fellBack = true {
+		err := walk(settings)
+
+		var walkFuncErr *walkFuncError
+
+		switch {
+		case err == nil:
+			if fellBack {
+				r.walkMemory.update(host, func(learned *LearnedWalkSettings) {
+					learned.GetNext, learned.MaxRepetitions = settings.getNext, settings.maxRepetitions
+				})
+			}
+
+			return nil
+		case ctx.Err() != nil, errors.As(err, &walkFuncErr), errors.Is(err, ErrTargetUnavailable):
+			return err
+		}
+
+		if settings, ok = settings.fallback(); !ok {
+			return err
+		}
+	}
+}
+
+func (r *GosnmpRequester) getWalkFailureReason(ctx context.Context, snmp *snmpHandler, oid string) error {
 	packet, err := snmp.GetNext([]string{oid})
 	if err != nil {
-		return r.maybeConvertErrToTimeout(err, []string{oid})
+		return r.maybeConvertErrToTimeout(ctx, err, []string{oid})
 	}
 
 	if len(packet.Variables) != 1 || packet.Variables[0].Type == gosnmp.NoSuchObject {
@@ -211,63 +675,121 @@ func (r *GosnmpRequester) getWalkFailureReason(snmp gosnmp.Handler, oid string)
 	return fmt.Errorf("end of mib: %s", oid)
 }
 
-func (*GosnmpRequester) maybeConvertErrToTimeout(err error, oids []string) error {
-	if strings.Contains(err.Error(), "timeout") {
-		return fmt.Errorf("timeout: %s", strings.Join(oids, ", "))
+func (*GosnmpRequester) maybeConvertErrToTimeout(ctx context.Context, err error, oids []string) error {
+	// gosnmp reports the expired deadline of the context by itself, possibly a moment before the context is done
+	if errors.Is(err, context.DeadlineExceeded) {
+		<-ctx.Done()
+	}
+
+	// errors caused by the closed connection are meaningless, the real reason is the context
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+
+	if !errors.Is(err, ErrTargetUnavailable) && strings.Contains(err.Error(), "timeout") {
+		return fmt.Errorf("%w: %s", ErrTimeout, strings.Join(oids, ", "))
 	}
 
 	return err
 }
 
-func (*GosnmpRequester) createSnmpHandler(apiRequest *ApiRequest) (gosnmp.Handler, error) {
-	snmp := gosnmp.NewHandler()
+// createSnmpHandler creates a connected SNMP handler bound to the context: once the context is done, the connection
+// is closed, which immediately interrupts any operation that is waiting for a response. The connection is taken from
+// the pool, if possible, and returned to it by Close.
+func (r *GosnmpRequester) createSnmpHandler(ctx context.Context, apiRequest *ApiRequest) (*snmpHandler, error) {
+	target, port, err := r.parseHost(apiRequest.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := r.admission.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := poolKey{
+		target:    target,
+		port:      port,
+		version:   gosnmp.SnmpVersion(apiRequest.Version),
+		community: apiRequest.Community,
+	}
+
+	snmp := r.connectionPool.get(key)
+	if snmp == nil {
+		snmp = &gosnmp.GoSNMP{
+			Target:             target,
+			Port:               port,
+			Community:          apiRequest.Community,
+			Version:            gosnmp.SnmpVersion(apiRequest.Version),
+			Timeout:            apiRequest.Timeout,
+			ExponentialTimeout: false,
+			MaxOids:            gosnmp.MaxOids,
+			Context:            ctx,
+		}
+
+		if err := snmp.Connect(); err != nil {
+			release()
+
+			return nil, err
+		}
+	}
+
+	// the handler sends the retries by itself
+	snmp.Context, snmp.Retries = ctx, 0
+
+	handler := &snmpHandler{
+		GoSNMP:      snmp,
+		release:     release,
+		limiter:     r.targetLimiter,
+		breaker:     r.circuitBreaker,
+		pool:        r.connectionPool,
+		poolKey:     key,
+		retryPolicy: r.retryPolicies.get(apiRequest),
+		timeout:     apiRequest.Timeout,
+		retries:     int(apiRequest.Retries),
+	}
+	handler.stopClosing = context.AfterFunc(ctx, handler.closeConn)
 
+	return handler, nil
+}
+
+// parseHost returns the target and the port of the host, which may be an IP address or a hostname, optionally
+// with the port.
+func (*GosnmpRequester) parseHost(host string) (string, uint16, error) {
 	var addrErr *net.AddrError
-	host, port, err := net.SplitHostPort(apiRequest.Host)
+
+	target, port, err := net.SplitHostPort(host)
 
 	switch {
 	case err == nil:
-		snmp.SetTarget(host)
-
 		port, err := strconv.ParseUint(port, 10, 16)
 		if err != nil {
-			return nil, fmt.Errorf("invalid host port: %w", err)
+			return "", 0, fmt.Errorf("invalid host port: %w", err)
 		}
 
-		snmp.SetPort(uint16(port))
+		return target, uint16(port), nil
 	case errors.As(err, &addrErr):
 		if addrErr.Err == "missing port in address" {
-			snmp.SetTarget(apiRequest.Host)
-
-			break
+			return host, 161, nil
 		}
 
 		if addrErr.Err == "too many colons in address" {
-			if addr, err := netip.ParseAddr(apiRequest.Host); err == nil {
-				snmp.SetTarget(addr.String())
-
-				break
+			if addr, err := netip.ParseAddr(host); err == nil {
+				return addr.String(), 161, nil
 			}
 		}
 
 		fallthrough
 	default:
-		return nil, fmt.Errorf("invalid host: %w", err)
+		return "", 0, fmt.Errorf("invalid host: %w", err)
 	}
-
-	snmp.SetCommunity(apiRequest.Community)
-	snmp.SetVersion(gosnmp.SnmpVersion(apiRequest.Version))
-	snmp.SetTimeout(apiRequest.Timeout)
-	snmp.SetExponentialTimeout(false)
-	snmp.SetRetries(int(apiRequest.Retries))
-
-	if err := snmp.Connect(); err != nil {
-		return nil, err
-	}
-
-	return snmp, nil
 }
 
 func NewGosnmpRequester(valueFormatter *ValueFormatter) *GosnmpRequester {
-	return &GosnmpRequester{valueFormatter: valueFormatter}
+	return &GosnmpRequester{
+		valueFormatter:      valueFormatter,
+		partialResultPolicy: PartialResultsNone,
+		walkStrategy:        WalkStrategyBulk,
+		walkMemory:          NewWalkMemory(),
+	}
 }
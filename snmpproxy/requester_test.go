@@ -2,17 +2,25 @@ package snmpproxy_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gosnmp/gosnmp"
 	"github.com/grongor/go-snmp-proxy/snmpproxy"
 	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 )
 
@@ -82,7 +90,7 @@ func TestGet(t *testing.T) {
 	apiRequest := apiRequest(get([]string{".1.3.6.1.2.1.25.2.3.1.2.1", ".1.3.6.1.2.1.25.2.3.1.2.4"}))
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.NoError(err)
 
 	assert.Equal(
@@ -92,7 +100,7 @@ func TestGet(t *testing.T) {
 				".1.3.6.1.2.1.25.2.3.1.2.4", ".1.3.6.1.2.1.25.2.1.9",
 			},
 		},
-		result,
+		result.Varbinds,
 	)
 }
 
@@ -102,7 +110,7 @@ func TestGetNext(t *testing.T) {
 	apiRequest := apiRequest(getNext([]string{".1.3.6.1.2.1.25.2.3.1.2", ".1.3.6.1.2.1.25.2.3.1.2.3"}))
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.NoError(err)
 
 	assert.Equal(
@@ -112,7 +120,7 @@ func TestGetNext(t *testing.T) {
 				".1.3.6.1.2.1.25.2.3.1.2.4", ".1.3.6.1.2.1.25.2.1.9",
 			},
 		},
-		result,
+		result.Varbinds,
 	)
 }
 
@@ -122,7 +130,7 @@ func TestWalk(t *testing.T) {
 	apiRequest := apiRequest(walk(".1.3.6.1.2.1.31.1.1.1.15"))
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.NoError(err)
 
 	assert.Equal(
@@ -133,199 +141,1536 @@ func TestWalk(t *testing.T) {
 				".1.3.6.1.2.1.31.1.1.1.15.1000005", uint(80000),
 			},
 		},
-		result,
+		result.Varbinds,
 	)
 }
 
+func TestWalkWithMaxResults(t *testing.T) {
+	assert := require.New(t)
+
+	request := walk(".1.3.6.1.2.1.31.1.1.1.15")
+	request.MaxResults = 2
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest(request))
+	assert.NoError(err)
+
+	assert.Equal(
+		[][]any{
+			{
+				".1.3.6.1.2.1.31.1.1.1.15.1000001", uint(100000),
+				".1.3.6.1.2.1.31.1.1.1.15.1000003", uint(60000),
+			},
+		},
+		result.Varbinds,
+	)
+	assert.Equal(
+		[]*snmpproxy.WalkCursor{{Root: ".1.3.6.1.2.1.31.1.1.1.15", Oid: ".1.3.6.1.2.1.31.1.1.1.15.1000003"}},
+		result.Cursors,
+	)
+
+	request.Cursor = result.Cursors[0]
+
+	result, err = requester.ExecuteRequest(context.Background(), apiRequest(request))
+	assert.NoError(err)
+
+	assert.Equal([][]any{{".1.3.6.1.2.1.31.1.1.1.15.1000005", uint(80000)}}, result.Varbinds)
+	assert.Nil(result.Cursors, "there must be no cursor for a finished walk")
+
+	request.Cursor = &snmpproxy.WalkCursor{
+		Root: ".1.3.6.1.2.1.31.1.1.1.15",
+		Oid:  ".1.3.6.1.2.1.31.1.1.1.15.1000005",
+	}
+
+	result, err = requester.ExecuteRequest(context.Background(), apiRequest(request))
+	assert.NoError(err)
+
+	assert.Equal([][]any{{}}, result.Varbinds)
+	assert.Nil(result.Cursors)
+}
+
+func TestWalkLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   snmpproxy.WalkLimits
+		requests []snmpproxy.Request
+		err      string
+	}{
+		{
+			name:     "walk varbinds",
+			limits:   snmpproxy.WalkLimits{MaxWalkVarbinds: 2},
+			requests: []snmpproxy.Request{walk(".1.3.6.1.2.1.31.1.1.1.15")},
+			err:      "limit exceeded, walk returned more than 2 varbinds: .1.3.6.1.2.1.31.1.1.1.15",
+		},
+		{
+			name:     "walk bytes",
+			limits:   snmpproxy.WalkLimits{MaxWalkBytes: 100},
+			requests: []snmpproxy.Request{walk(".1.3.6.1.2.1.31.1.1.1.15")},
+			err:      "limit exceeded, walk returned more than 100 bytes: .1.3.6.1.2.1.31.1.1.1.15",
+		},
+		{
+			name:     "request varbinds",
+			limits:   snmpproxy.WalkLimits{MaxWalkVarbinds: 3, MaxRequestVarbinds: 5},
+			requests: []snmpproxy.Request{walk(".1.3.6.1.2.1.31.1.1.1.15"), walk(".1.3.6.1.2.1.31.1.1.1.15")},
+			err:      "limit exceeded, walks of the request returned more than 5 varbinds: .1.3.6.1.2.1.31.1.1.1.15",
+		},
+		{
+			name:     "request bytes",
+			limits:   snmpproxy.WalkLimits{MaxWalkBytes: 120, MaxRequestBytes: 200},
+			requests: []snmpproxy.Request{walk(".1.3.6.1.2.1.31.1.1.1.15"), walk(".1.3.6.1.2.1.31.1.1.1.15")},
+			err:      "limit exceeded, walks of the request returned more than 200 bytes: .1.3.6.1.2.1.31.1.1.1.15",
+		},
+		{
+			name:     "within limits",
+			limits:   snmpproxy.WalkLimits{MaxWalkVarbinds: 3, MaxWalkBytes: 120, MaxRequestVarbinds: 6},
+			requests: []snmpproxy.Request{walk(".1.3.6.1.2.1.31.1.1.1.15"), walk(".1.3.6.1.2.1.31.1.1.1.15")},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			requester.SetWalkLimits(test.limits)
+
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest(test.requests...))
+
+			if test.err == "" {
+				assert.NoError(err)
+				assert.Len(result.Varbinds, len(test.requests))
+			} else {
+				assert.Nil(result)
+				assert.ErrorIs(err, snmpproxy.ErrLimitExceeded)
+				assert.EqualError(err, test.err)
+			}
+		})
+	}
+}
+
+func TestWalkWithFormatOverridesReplaced(t *testing.T) {
+	assert := require.New(t)
+
+	oids := []string{".1.2.3.1", ".1.2.3.2", ".1.2.3.3", ".1.2.3.4"}
+
+	formatter := snmpproxy.NewValueFormatter(mib.NewDataProvider(nil))
+	overrides, err := snmpproxy.NewFormatOverrides(
+		[]snmpproxy.FormatOverride{{Prefix: ".1.2.3", Format: snmpproxy.OverrideFormatInteger, Scale: 2}},
+		nil,
+	)
+	assert.NoError(err)
+
+	var received atomic.Int32
+
+	// the overrides are replaced in the middle of the walk, but all the values are formatted alike
+	host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+		if received.Add(1) == 2 {
+			formatter.SetFormatOverrides(overrides)
+		}
+
+		return walkResponse(oids, request.Variables[0].Name, 2), gosnmp.NoError
+	})
+
+	request := walk(".1.2.3")
+	request.MaxRepetitions = 2
+
+	apiRequest := apiRequest(request)
+	apiRequest.Host = host
+
+	requester := snmpproxy.NewGosnmpRequester(formatter)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+	assert.Equal([][]any{{".1.2.3.1", 1, ".1.2.3.2", 1, ".1.2.3.3", 1, ".1.2.3.4", 1}}, result.Varbinds)
+	assert.Greater(received.Load(), int32(1))
+}
+
+func TestWalkWithOidNotIncreasing(t *testing.T) {
+	tests := []struct {
+		name    string
+		version gosnmp.SnmpVersion
+		oids    []string
+		err     string
+	}{
+		{
+			name:    "same OID as requested",
+			version: gosnmp.Version2c,
+			oids:    []string{".1.2.3.1", ".1.2.3.1"},
+			err:     "OID not increasing: .1.2.3.1 after .1.2.3.1",
+		},
+		{
+			name:    "decreasing OID in the same response",
+			version: gosnmp.Version2c,
+			oids:    []string{".1.2.3.1", ".1.2.3.10", ".1.2.3.9"},
+			err:     "OID not increasing: .1.2.3.9 after .1.2.3.10",
+		},
+		{
+			name:    "looping agent",
+			version: gosnmp.Version1,
+			oids:    []string{".1.2.3.1", ".1.2.3.2.1", ".1.2.3.2"},
+			err:     "OID not increasing: .1.2.3.2 after .1.2.3.2.1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			var (
+				mu        sync.Mutex
+				responses int
+			)
+
+			apiRequest := apiRequest(walk(".1.2.3"))
+			apiRequest.Version = snmpproxy.SnmpVersion(test.version)
+			apiRequest.Host = startAgent(t, func(*gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				if test.version == gosnmp.Version1 {
+					mu.Lock()
+					defer mu.Unlock()
+
+					responses++
+
+					return []gosnmp.SnmpPDU{{Name: test.oids[responses-1], Type: gosnmp.Integer, Value: 1}}, gosnmp.NoError
+				}
+
+				dataUnits := make([]gosnmp.SnmpPDU, 0, len(test.oids))
+				for _, oid := range test.oids {
+					dataUnits = append(dataUnits, gosnmp.SnmpPDU{Name: oid, Type: gosnmp.Integer, Value: 1})
+				}
+
+				return dataUnits, gosnmp.NoError
+			})
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+			assert.Nil(result)
+			assert.ErrorIs(err, snmpproxy.ErrOidNotIncreasing)
+			assert.EqualError(err, test.err)
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(walk(".1.3.6.1.2.1.31.1.1.1.15"), get([]string{".1.3.6.1.2.1.25.2.3.1.2.1"}))
+
+	var (
+		mu       sync.Mutex
+		varbinds [][]any
+	)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	cursors, err := requester.StreamRequest(
+		context.Background(),
+		apiRequest,
+		func(requestNo int, oid string, value any) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			varbinds = append(varbinds, []any{requestNo, oid, value})
+
+			return nil
+		},
+	)
+	assert.NoError(err)
+	assert.Nil(cursors)
+
+	assert.ElementsMatch(
+		[][]any{
+			{0, ".1.3.6.1.2.1.31.1.1.1.15.1000001", uint(100000)},
+			{0, ".1.3.6.1.2.1.31.1.1.1.15.1000003", uint(60000)},
+			{0, ".1.3.6.1.2.1.31.1.1.1.15.1000005", uint(80000)},
+			{1, ".1.3.6.1.2.1.25.2.3.1.2.1", ".1.3.6.1.2.1.25.2.1.2"},
+		},
+		varbinds,
+	)
+}
+
+func TestStreamAborted(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(walk(".1.3.6.1.2.1.31.1.1.1.15"))
+
+	var varbinds int
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	cursors, err := requester.StreamRequest(
+		context.Background(),
+		apiRequest,
+		func(int, string, any) error {
+			varbinds++
+
+			return errors.New("client is gone")
+		},
+	)
+	assert.EqualError(err, "client is gone")
+	assert.Nil(cursors)
+	assert.Equal(1, varbinds)
+}
+
 func TestWalkWithSnmpVersion1(t *testing.T) {
 	assert := require.New(t)
 
-	apiRequest := apiRequest(walk(".1.3.6.1.2.1.31.1.1.1.15"))
-	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
+	apiRequest := apiRequest(walk(".1.3.6.1.2.1.31.1.1.1.15"))
+	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+
+	assert.Equal(
+		[][]any{
+			{
+				".1.3.6.1.2.1.31.1.1.1.15.1000001", uint(100000),
+				".1.3.6.1.2.1.31.1.1.1.15.1000003", uint(60000),
+				".1.3.6.1.2.1.31.1.1.1.15.1000005", uint(80000),
+			},
+		},
+		result.Varbinds,
+	)
+}
+
+func TestWalkWholeTree(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(walk(".1.3"))
+
+	mibDataProvider := snmpproxy.NewValueFormatter(
+		mib.NewDataProvider(
+			mib.Objects{
+				".1.3.6.1.2.1.2.2.1.2":  {DisplayHint: mib.DisplayHintString},
+				".1.3.6.1.2.1.4.22.1.2": {DisplayHint: mib.DisplayHintHexadecimal},
+			},
+		),
+	)
+	requester := snmpproxy.NewGosnmpRequester(mibDataProvider)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+
+	assert.Equal(
+		[][]any{
+			{
+				".1.3.6.1.2.1.1.1.0",
+				`Cisco IOS Software, C2960S Software (C2960S-UNIVERSALK9-M), Version 12.2(58)SE2, RELEASE SOFTWARE (fc1)
+Technical Support: http://www.cisco.com/techsupport
+Copyright (c) 1986-2011 by "Cisco Systems, Inc."
+Compiled Thu 21-Jul-11 02:22 by prod_rel_team`,
+				".1.3.6.1.2.1.1.3.0", uint32(293718542),
+				".1.3.6.1.2.1.2.2.1.2.47", "Ethernet47",
+				".1.3.6.1.2.1.2.2.1.2.48", "Ethernet48",
+				".1.3.6.1.2.1.2.2.1.2.49001", "Ethernet49/1",
+				".1.3.6.1.2.1.2.2.1.2.50001", "Ethernet50/1",
+				".1.3.6.1.2.1.2.2.1.2.1000008", "Port-Channel8",
+				".1.3.6.1.2.1.2.2.1.2.1000009", "Port-Channel9",
+				".1.3.6.1.2.1.2.2.1.2.2002002", "Vlan2002",
+				".1.3.6.1.2.1.2.2.1.2.2002019", "Vlan2019",
+				".1.3.6.1.2.1.2.2.1.2.2002020", "Vlan2020",
+				".1.3.6.1.2.1.2.2.1.2.5000000", "Loopback0",
+				".1.3.6.1.2.1.2.2.1.14.8", uint(0),
+				".1.3.6.1.2.1.2.2.1.14.9", uint(226),
+				".1.3.6.1.2.1.2.2.1.14.10", uint(256),
+				".1.3.6.1.2.1.2.2.1.14.11", uint(296),
+				".1.3.6.1.2.1.4.20.1.1.10.100.192.2", "10.100.192.2",
+				".1.3.6.1.2.1.4.20.1.1.10.110.27.254", "10.110.27.254",
+				".1.3.6.1.2.1.4.20.1.1.66.208.216.74", "66.208.216.74",
+				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.97", "91 E2 BA E3 5A 61",
+				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.99", "53 54 00 5F 41 D0",
+				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.100", "53 54 00 4C 5A 5D",
+				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.102", "53 54 00 A9 A8 3B",
+				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.104", "53 54 00 5A A0 CA",
+				".1.3.6.1.2.1.25.2.3.1.2.1", ".1.3.6.1.2.1.25.2.1.2",
+				".1.3.6.1.2.1.25.2.3.1.2.2", ".1.3.6.1.2.1.25.2.1.2",
+				".1.3.6.1.2.1.25.2.3.1.2.3", ".1.3.6.1.2.1.25.2.1.2",
+				".1.3.6.1.2.1.25.2.3.1.2.4", ".1.3.6.1.2.1.25.2.1.9",
+				".1.3.6.1.2.1.31.1.1.1.6.46", uint64(1884401752869190),
+				".1.3.6.1.2.1.31.1.1.1.6.47", uint64(1883620653799494),
+				".1.3.6.1.2.1.31.1.1.1.6.48", uint64(1884283891426650),
+				".1.3.6.1.2.1.31.1.1.1.6.49001", uint64(2494191363092125),
+				".1.3.6.1.2.1.31.1.1.1.6.50001", uint64(17658827020872235),
+				".1.3.6.1.2.1.31.1.1.1.15.1000001", uint(100000),
+				".1.3.6.1.2.1.31.1.1.1.15.1000003", uint(60000),
+				".1.3.6.1.2.1.31.1.1.1.15.1000005", uint(80000),
+				".1.3.6.1.2.1.47.1.1.1.1.13.30", "4E 4D 2D 33 32 41 20 20 20 20 20 20 20 20 20 20 20 20 FF FF FF FF FF FF FF",
+				".1.3.6.1.6.3.10.2.1.3.0", 2937024,
+			},
+		},
+		result.Varbinds,
+	)
+}
+
+func TestWalkLastMibElement(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(walk(".1.7"))
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+
+	assert.Equal([][]any{{".1.7.8.9", "Don't know what I'm"}}, result.Varbinds)
+}
+
+func TestWalkLastMibElementAndSnmpVersion1(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(walk(".1.7"))
+	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+
+	assert.Equal([][]any{{".1.7.8.9", "Don't know what I'm"}}, result.Varbinds)
+}
+
+func TestMultipleRequests(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(
+		get([]string{".1.3.6.1.2.1.1.3.0"}),
+		walk(".1.7"),
+		getNext([]string{".1.3.6.1.2.1.2.2.1.14.9", ".1.3.6.1.2.1.2.2.1.14.10"}),
+	)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+
+	assert.Equal(
+		[][]any{
+			{".1.3.6.1.2.1.1.3.0", uint32(293718542)},
+			{".1.7.8.9", "Don't know what I'm"},
+			{
+				".1.3.6.1.2.1.2.2.1.14.10", uint(256),
+				".1.3.6.1.2.1.2.2.1.14.11", uint(296),
+			},
+		},
+		result.Varbinds,
+	)
+}
+
+func TestMultipleRequestsWithSingleError(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(
+		get([]string{".1.3.6.1.2.1.1.3.0"}),
+		walk(".1.7"),
+		getNext([]string{".1.3.6.1.2.1.2.2.1.14.9", ".1.7.9"}),
+	)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.Nil(result)
+	assert.EqualError(err, "end of mib: .1.7.9")
+}
+
+func TestMultipleRequestsExecutedIndependently(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(
+		get([]string{".1.3.6.1.2.1.1.3.0"}),
+		walk(".1.7"),
+		getNext([]string{".1.3.6.1.2.1.2.2.1.14.9", ".1.7.9"}),
+	)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+	// the failed request doesn't cancel the others
+	result, errs := requester.ExecuteIndependently(context.Background(), apiRequest)
+	assert.Equal(
+		[][]any{{".1.3.6.1.2.1.1.3.0", uint32(293718542)}, {".1.7.8.9", "Don't know what I'm"}, nil},
+		result.Varbinds,
+	)
+	assert.Len(errs, 3)
+	assert.NoError(errs[0])
+	assert.NoError(errs[1])
+	assert.EqualError(errs[2], "end of mib: .1.7.9")
+}
+
+func TestMultipleRequestsAllError(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(
+		get([]string{".1.7.9"}),
+		walk(".1.7.9"),
+		getNext([]string{".1.3.6.1.2.1.2.2.1.14.9", ".1.7.9"}),
+	)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.Nil(result)
+	assert.Error(err)
+	assert.Regexp(`(end of mib|no such instance): .1.7.9`, err.Error())
+}
+
+func TestWalkWithTimeout(t *testing.T) {
+	assert := require.New(t)
+
+	apiRequest := apiRequest(walk(".1.15"))
+	apiRequest.Host = "8.8.8.8"
+	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
+	apiRequest.Timeout = time.Millisecond
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.Nil(result)
+	assert.EqualError(err, "timeout: .1.15")
+}
+
+func TestWalkCancelled(t *testing.T) {
+	assert := require.New(t)
+
+	// an agent that never responds
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	defer conn.Close()
+
+	apiRequest := apiRequest(walk(".1.3"), get([]string{".1.3.6.1.2.1.1.1.0"}))
+	apiRequest.Host = conn.LocalAddr().String()
+	apiRequest.Timeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*100, cancel)
+
+	start := time.Now()
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	result, err := requester.ExecuteRequest(ctx, apiRequest)
+	assert.Nil(result)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Less(time.Since(start), time.Second)
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         snmpproxy.PartialResultPolicy
+		expectedResult *snmpproxy.Result
+	}{
+		{name: "no partial results", policy: snmpproxy.PartialResultsNone},
+		{
+			name:           "completed results",
+			policy:         snmpproxy.PartialResultsCompleted,
+			expectedResult: &snmpproxy.Result{Varbinds: [][]any{nil, nil}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			// an agent that never responds
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			assert.NoError(err)
+
+			defer conn.Close()
+
+			apiRequest := apiRequest(walk(".1.3"), get([]string{".1.3.6.1.2.1.1.1.0"}))
+			apiRequest.Host = conn.LocalAddr().String()
+			apiRequest.Timeout = time.Minute
+			apiRequest.Deadline = time.Millisecond * 100
+
+			start := time.Now()
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			requester.SetPartialResultPolicy(test.policy)
+
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+			assert.Equal(test.expectedResult, result)
+			assert.ErrorIs(err, snmpproxy.ErrDeadlineExceeded)
+			assert.EqualError(err, "request deadline exceeded (100ms)")
+			assert.Less(time.Since(start), time.Second)
+		})
+	}
+}
+
+func TestWalkWithPartialResults(t *testing.T) {
+	tests := []struct {
+		name           string
+		partialResults bool
+		cursor         *snmpproxy.WalkCursor
+		deadline       time.Duration
+		expectedResult *snmpproxy.Result
+		err            string
+	}{
+		{
+			name: "partial results not requested",
+			err:  "timeout: .1.2.3",
+		},
+		{
+			name:           "timeout",
+			partialResults: true,
+			expectedResult: &snmpproxy.Result{
+				Varbinds: [][]any{{".1.2.3.1", 1, ".1.2.3.2", 1}},
+				Cursors:  []*snmpproxy.WalkCursor{{Root: ".1.2.3", Oid: ".1.2.3.2"}},
+			},
+			err: "timeout: .1.2.3 (walk incomplete, stopped after .1.2.3.2)",
+		},
+		{
+			name:           "deadline exceeded",
+			partialResults: true,
+			deadline:       time.Millisecond * 100,
+			expectedResult: &snmpproxy.Result{
+				Varbinds: [][]any{{".1.2.3.1", 1, ".1.2.3.2", 1}},
+				Cursors:  []*snmpproxy.WalkCursor{{Root: ".1.2.3", Oid: ".1.2.3.2"}},
+			},
+			err: "request deadline exceeded (100ms) (walk incomplete, stopped after .1.2.3.2)",
+		},
+		{
+			name:           "timeout right after the cursor",
+			partialResults: true,
+			cursor:         &snmpproxy.WalkCursor{Root: ".1.2.3", Oid: ".1.2.3.5"},
+			expectedResult: &snmpproxy.Result{
+				Varbinds: [][]any{{}},
+				Cursors:  []*snmpproxy.WalkCursor{{Root: ".1.2.3", Oid: ".1.2.3.5"}},
+			},
+			err: "timeout: .1.2.3 (walk incomplete, stopped after .1.2.3.5)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			request := walk(".1.2.3")
+			request.MaxRepetitions = 2
+			request.PartialResults = test.partialResults
+			request.Cursor = test.cursor
+
+			apiRequest := apiRequest(request)
+			apiRequest.Timeout = time.Millisecond * 200
+			apiRequest.Deadline = test.deadline
+			apiRequest.Host = startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				// only the first page of the walk is returned, then the agent stops responding
+				if request.Variables[0].Name != ".1.2.3" {
+					return nil, gosnmp.NoError
+				}
+
+				return walkResponse([]string{".1.2.3.1", ".1.2.3.2"}, request.Variables[0].Name, 2), gosnmp.NoError
+			})
+
+			if test.deadline != 0 {
+				apiRequest.Timeout = time.Minute
+			}
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+			assert.Equal(test.expectedResult, result)
+			assert.EqualError(err, test.err)
+		})
+	}
+}
+
+func TestWalkStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy snmpproxy.WalkStrategy
+		// the agent ignores GetBulk requests with more max repetitions than this
+		maxRepetitions uint32
+		// expected requests, for both the first and the second walk
+		expectedRequests [2][]string
+		err              string
+	}{
+		{
+			name:             "bulk",
+			strategy:         snmpproxy.WalkStrategyBulk,
+			maxRepetitions:   100,
+			expectedRequests: [2][]string{{"bulk 50"}, {"bulk 50"}},
+		},
+		{
+			name:             "bulk with a broken agent",
+			strategy:         snmpproxy.WalkStrategyBulk,
+			maxRepetitions:   0,
+			expectedRequests: [2][]string{{"bulk 50"}, {"bulk 50"}},
+			err:              "timeout: .1.2.3",
+		},
+		{
+			name:             "getnext",
+			strategy:         snmpproxy.WalkStrategyGetNext,
+			maxRepetitions:   0,
+			expectedRequests: [2][]string{{"next", "next", "next", "next"}, {"next", "next", "next", "next"}},
+		},
+		{
+			name:           "auto with lower max repetitions",
+			strategy:       snmpproxy.WalkStrategyAuto,
+			maxRepetitions: 20,
+			expectedRequests: [2][]string{
+				{"bulk 50", "bulk 12"},
+				{"bulk 12"},
+			},
+		},
+		{
+			name:           "auto with getnext",
+			strategy:       snmpproxy.WalkStrategyAuto,
+			maxRepetitions: 0,
+			expectedRequests: [2][]string{
+				{"bulk 50", "bulk 12", "next", "next", "next", "next"},
+				{"next", "next", "next", "next"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			oids := []string{".1.2.3.1", ".1.2.3.2", ".1.2.3.3"}
+
+			var (
+				mu       sync.Mutex
+				requests []string
+			)
+
+			host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				repetitions := 1
+				if request.PDUType == gosnmp.GetBulkRequest {
+					requests = append(requests, fmt.Sprintf("bulk %d", request.MaxRepetitions))
+
+					if request.MaxRepetitions > test.maxRepetitions {
+						return nil, gosnmp.NoError
+					}
+
+					repetitions = int(request.MaxRepetitions)
+				} else {
+					requests = append(requests, "next")
+				}
+
+				return walkResponse(oids, request.Variables[0].Name, repetitions), gosnmp.NoError
+			})
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			requester.SetWalkStrategy(test.strategy)
+
+			for _, expectedRequests := range test.expectedRequests {
+				mu.Lock()
+				requests = nil
+				mu.Unlock()
+
+				apiRequest := apiRequest(walk(".1.2.3"))
+				apiRequest.Host = host
+				apiRequest.Timeout = time.Millisecond * 100
+
+				result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+
+				mu.Lock()
+				assert.Equal(expectedRequests, requests)
+				mu.Unlock()
+
+				if test.err != "" {
+					assert.EqualError(err, test.err)
+
+					continue
+				}
+
+				assert.NoError(err)
+				assert.Equal([][]any{{".1.2.3.1", 1, ".1.2.3.2", 1, ".1.2.3.3", 1}}, result.Varbinds)
+			}
+		})
+	}
+}
+
+func TestWalkWithAutoMaxRepetitions(t *testing.T) {
+	tests := []struct {
+		name string
+		// the agent returns at most this many varbinds
+		maxVarbinds int
+		// the agent responds with tooBig error if more max repetitions are requested
+		tooBig uint32
+		// the agent ignores every other request, so that each of them is retried
+		lossy bool
+		// max repetitions of the first and the second walk
+		expectedRequests [2][]uint32
+		expectedLearned  uint32
+	}{
+		{
+			name:             "fast agent",
+			maxVarbinds:      100,
+			tooBig:           100,
+			expectedRequests: [2][]uint32{{20, 26}, {26, 33}},
+			expectedLearned:  33,
+		},
+		{
+			name:             "truncated responses",
+			maxVarbinds:      7,
+			tooBig:           100,
+			expectedRequests: [2][]uint32{{20, 7, 9, 7, 9}, {9, 7, 9, 7, 9}},
+			expectedLearned:  9,
+		},
+		{
+			name:             "too big responses",
+			maxVarbinds:      100,
+			tooBig:           10,
+			expectedRequests: [2][]uint32{{20, 10, 13, 6, 8, 11, 5, 7}, {7, 9, 12, 6, 8, 11, 5}},
+			expectedLearned:  5,
+		},
+		{
+			// only the latency of the retries that were responded to counts
+			name:             "lost requests",
+			maxVarbinds:      100,
+			tooBig:           100,
+			lossy:            true,
+			expectedRequests: [2][]uint32{{20, 20, 26, 26}, {26, 26, 33, 33}},
+			expectedLearned:  33,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			oids := make([]string, 30)
+			for i := range oids {
+				oids[i] = ".1.2.3." + strconv.Itoa(i+1)
+			}
+
+			var (
+				mu       sync.Mutex
+				requests []uint32
+			)
+
+			host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				requests = append(requests, request.MaxRepetitions)
+
+				if test.lossy && len(requests)%2 == 1 {
+					return nil, gosnmp.NoError
+				}
+
+				if request.MaxRepetitions > test.tooBig {
+					return []gosnmp.SnmpPDU{}, gosnmp.TooBig
+				}
+
+				repetitions := min(int(request.MaxRepetitions), test.maxVarbinds)
+
+				return walkResponse(oids, request.Variables[0].Name, repetitions), gosnmp.NoError
+			})
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+			for _, expectedRequests := range test.expectedRequests {
+				mu.Lock()
+				requests = nil
+				mu.Unlock()
+
+				request := walk(".1.2.3")
+				request.AutoMaxRepetitions = true
+
+				apiRequest := apiRequest(request)
+				apiRequest.Host = host
+
+				if test.lossy {
+					apiRequest.Timeout = time.Millisecond * 50
+					apiRequest.Retries = 1
+				}
+
+				result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+				assert.NoError(err)
+				assert.Len(result.Varbinds[0], len(oids)*2)
+
+				mu.Lock()
+				assert.Equal(expectedRequests, requests)
+				mu.Unlock()
+			}
+
+			learned, ok := requester.WalkMemory().Get(host)
+			assert.True(ok)
+			assert.Equal(test.expectedLearned, learned.AutoMaxRepetitions)
+		})
+	}
+}
+
+func TestParallelWalk(t *testing.T) {
+	// a table with 3 columns and 10 rows
+	var oids []string
+
+	for column := 1; column <= 3; column++ {
+		for row := 1; row <= 10; row++ {
+			oids = append(oids, fmt.Sprintf(".1.2.3.1.%d.%d", column, row))
+		}
+	}
+
+	expectedVarbinds := func(oids []string) []any {
+		varbinds := make([]any, 0, len(oids)*2)
+		for _, oid := range oids {
+			varbinds = append(varbinds, oid, 1)
+		}
+
+		return varbinds
+	}
+
+	tests := []struct {
+		name   string
+		root   string
+		cursor *snmpproxy.WalkCursor
+		// the agent stops responding to GetBulk requests for this OID
+		brokenOid      string
+		maxResults     uint32
+		limits         snmpproxy.WalkLimits
+		expectedResult *snmpproxy.Result
+		expectedBulks  []string
+		err            string
+	}{
+		{
+			name:           "whole table",
+			root:           ".1.2.3",
+			expectedResult: &snmpproxy.Result{Varbinds: [][]any{expectedVarbinds(oids)}},
+			expectedBulks: []string{
+				".1.2.3.1.1", ".1.2.3.1.1.4", ".1.2.3.1.1.8",
+				".1.2.3.1.2", ".1.2.3.1.2.4", ".1.2.3.1.2.8",
+				".1.2.3.1.3", ".1.2.3.1.3.4", ".1.2.3.1.3.8",
+			},
+		},
+		{
+			name:           "single column isn't split",
+			root:           ".1.2.3.1.2",
+			expectedResult: &snmpproxy.Result{Varbinds: [][]any{expectedVarbinds(oids[10:20])}},
+			expectedBulks:  []string{".1.2.3.1.2", ".1.2.3.1.2.4", ".1.2.3.1.2.8"},
+		},
+		{
+			name:       "max results",
+			root:       ".1.2.3",
+			maxResults: 15,
+			expectedResult: &snmpproxy.Result{
+				Varbinds: [][]any{expectedVarbinds(oids[:15])},
+				Cursors:  []*snmpproxy.WalkCursor{{Root: ".1.2.3", Oid: ".1.2.3.1.2.5"}},
+			},
+		},
+		{
+			name:           "continued walk",
+			root:           ".1.2.3",
+			cursor:         &snmpproxy.WalkCursor{Root: ".1.2.3", Oid: ".1.2.3.1.2.5"},
+			expectedResult: &snmpproxy.Result{Varbinds: [][]any{expectedVarbinds(oids[15:])}},
+			expectedBulks: []string{
+				".1.2.3.1.2.5", ".1.2.3.1.2.9",
+				".1.2.3.1.3", ".1.2.3.1.3.4", ".1.2.3.1.3.8",
+			},
+		},
+		{
+			name:      "partial results",
+			root:      ".1.2.3",
+			brokenOid: ".1.2.3.1.2.4",
+			expectedResult: &snmpproxy.Result{
+				Varbinds: [][]any{expectedVarbinds(oids[:14])},
+				Cursors:  []*snmpproxy.WalkCursor{{Root: ".1.2.3", Oid: ".1.2.3.1.2.4"}},
+			},
+			err: "timeout: .1.2.3 (walk incomplete, stopped after .1.2.3.1.2.4)",
+		},
+		{
+			// the first column never finishes, so the others are buffered until they exceed the limit
+			name:      "limits of buffered columns",
+			root:      ".1.2.3",
+			brokenOid: ".1.2.3.1.1.4",
+			limits:    snmpproxy.WalkLimits{MaxWalkVarbinds: 10},
+			err:       "limit exceeded, walk returned more than 10 varbinds: .1.2.3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			var (
+				mu    sync.Mutex
+				bulks []string
+			)
+
+			host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				oid := request.Variables[0].Name
+				if request.PDUType != gosnmp.GetBulkRequest {
+					return walkResponse(oids, oid, 1), gosnmp.NoError
+				}
+
+				mu.Lock()
+				bulks = append(bulks, oid)
+				mu.Unlock()
+
+				if oid == test.brokenOid {
+					return nil, gosnmp.NoError
+				}
+
+				return walkResponse(oids, oid, int(request.MaxRepetitions)), gosnmp.NoError
+			})
+
+			request := walk(test.root)
+			request.MaxRepetitions = 4
+			request.Parallel = 3
+			request.MaxResults = test.maxResults
+			request.Cursor = test.cursor
+			request.PartialResults = true
+
+			apiRequest := apiRequest(request)
+			apiRequest.Host = host
+			apiRequest.Timeout = time.Millisecond * 200
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			requester.SetWalkLimits(test.limits)
+
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+
+			if test.err == "" {
+				assert.NoError(err)
+			} else {
+				assert.EqualError(err, test.err)
+			}
+
+			assert.Equal(test.expectedResult, result)
+
+			if test.expectedBulks != nil {
+				mu.Lock()
+				assert.ElementsMatch(test.expectedBulks, bulks)
+				mu.Unlock()
+			}
+		})
+	}
+}
+
+func TestCoalescedGets(t *testing.T) {
+	tests := []struct {
+		name    string
+		version gosnmp.SnmpVersion
+		// the agent responds with tooBig error to PDUs with more OIDs than this
+		maxOids int
+		// the agent doesn't know this OID
+		unknownOid     string
+		expectedPdus   []int
+		expectedResult *snmpproxy.Result
+		err            string
+	}{
+		{
+			name:         "single PDU",
+			version:      gosnmp.Version2c,
+			maxOids:      10,
+			expectedPdus: []int{5},
+			expectedResult: &snmpproxy.Result{Varbinds: [][]any{
+				{".1.2.1", 1, ".1.2.2", 1},
+				{".1.2.3", 1},
+				{".1.2.4", 1, ".1.2.5", 1},
+			}},
+		},
+		{
+			name:         "too big",
+			version:      gosnmp.Version2c,
+			maxOids:      2,
+			expectedPdus: []int{5, 2, 2, 1},
+			expectedResult: &snmpproxy.Result{Varbinds: [][]any{
+				{".1.2.1", 1, ".1.2.2", 1},
+				{".1.2.3", 1},
+				{".1.2.4", 1, ".1.2.5", 1},
+			}},
+		},
+		{
+			name:         "no such object",
+			version:      gosnmp.Version2c,
+			maxOids:      10,
+			unknownOid:   ".1.2.3",
+			expectedPdus: []int{5},
+			err:          "no such object: .1.2.3",
+		},
+		{
+			name:         "error status with SNMP v1",
+			version:      gosnmp.Version1,
+			maxOids:      10,
+			unknownOid:   ".1.2.3",
+			expectedPdus: []int{5, 2, 1, 2},
+			err:          "no such instance: .1.2.3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			var (
+				mu   sync.Mutex
+				pdus []int
+			)
+
+			apiRequest := apiRequest(
+				get([]string{".1.2.1", ".1.2.2"}),
+				get([]string{".1.2.3"}),
+				get([]string{".1.2.4", ".1.2.5"}),
+			)
+			apiRequest.Version = snmpproxy.SnmpVersion(test.version)
+			apiRequest.Host = startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				mu.Lock()
+				pdus = append(pdus, len(request.Variables))
+				mu.Unlock()
+
+				if len(request.Variables) > test.maxOids {
+					return []gosnmp.SnmpPDU{}, gosnmp.TooBig
+				}
+
+				dataUnits := make([]gosnmp.SnmpPDU, 0, len(request.Variables))
+
+				for _, variable := range request.Variables {
+					if variable.Name != test.unknownOid {
+						dataUnits = append(dataUnits, gosnmp.SnmpPDU{Name: variable.Name, Type: gosnmp.Integer, Value: 1})
+
+						continue
+					}
+
+					if test.version == gosnmp.Version1 {
+						return request.Variables, gosnmp.NoSuchName
+					}
+
+					dataUnits = append(dataUnits, gosnmp.SnmpPDU{Name: variable.Name, Type: gosnmp.NoSuchObject})
+				}
+
+				return dataUnits, gosnmp.NoError
+			})
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+
+			if test.err == "" {
+				assert.NoError(err)
+			} else {
+				assert.EqualError(err, test.err)
+			}
+
+			assert.Equal(test.expectedResult, result)
+
+			mu.Lock()
+			assert.Equal(test.expectedPdus, pdus)
+			mu.Unlock()
+		})
+	}
+}
+
+func TestTargetLimits(t *testing.T) {
+	tests := []struct {
+		name               string
+		limits             *snmpproxy.TargetLimits
+		expectedInFlight   int32
+		expectedWaitedPdus uint64
+	}{
+		{name: "unlimited", expectedInFlight: 4},
+		{name: "max in flight", limits: &snmpproxy.TargetLimits{MaxInFlight: 2}, expectedInFlight: 2, expectedWaitedPdus: 8},
+		{
+			name:               "sequential",
+			limits:             &snmpproxy.TargetLimits{MaxInFlight: 2, Sequential: []string{"127.0.0.1"}},
+			expectedInFlight:   1,
+			expectedWaitedPdus: 8,
+		},
+		{
+			name:               "other host sequential",
+			limits:             &snmpproxy.TargetLimits{Sequential: []string{"127.0.0.2"}},
+			expectedInFlight:   4,
+			expectedWaitedPdus: 0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
+
+			registry := prometheus.NewRegistry()
+			prometheus.DefaultRegisterer = registry
 
-	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.NoError(err)
+			oids := []string{".1.2.1.1", ".1.2.2.1", ".1.2.3.1", ".1.2.4.1"}
 
-	assert.Equal(
-		[][]any{
-			{
-				".1.3.6.1.2.1.31.1.1.1.15.1000001", uint(100000),
-				".1.3.6.1.2.1.31.1.1.1.15.1000003", uint(60000),
-				".1.3.6.1.2.1.31.1.1.1.15.1000005", uint(80000),
-			},
-		},
-		result,
-	)
-}
+			var inFlight, maxInFlight atomic.Int32
 
-func TestWalkWholeTree(t *testing.T) {
-	assert := require.New(t)
+			host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
 
-	apiRequest := apiRequest(walk(".1.3"))
+				for {
+					if observed := maxInFlight.Load(); current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+						break
+					}
+				}
 
-	mibDataProvider := snmpproxy.NewValueFormatter(
-		mib.NewDataProvider(
-			mib.DisplayHints{
-				".1.3.6.1.2.1.2.2.1.2":  mib.DisplayHintString,
-				".1.3.6.1.2.1.4.22.1.2": mib.DisplayHintHexadecimal,
-			},
-		),
-	)
-	requester := snmpproxy.NewGosnmpRequester(mibDataProvider)
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.NoError(err)
+				time.Sleep(time.Millisecond * 50)
 
-	assert.Equal(
-		[][]any{
-			{
-				".1.3.6.1.2.1.1.1.0",
-				`Cisco IOS Software, C2960S Software (C2960S-UNIVERSALK9-M), Version 12.2(58)SE2, RELEASE SOFTWARE (fc1)
-Technical Support: http://www.cisco.com/techsupport
-Copyright (c) 1986-2011 by "Cisco Systems, Inc."
-Compiled Thu 21-Jul-11 02:22 by prod_rel_team`,
-				".1.3.6.1.2.1.1.3.0", uint32(293718542),
-				".1.3.6.1.2.1.2.2.1.2.47", "Ethernet47",
-				".1.3.6.1.2.1.2.2.1.2.48", "Ethernet48",
-				".1.3.6.1.2.1.2.2.1.2.49001", "Ethernet49/1",
-				".1.3.6.1.2.1.2.2.1.2.50001", "Ethernet50/1",
-				".1.3.6.1.2.1.2.2.1.2.1000008", "Port-Channel8",
-				".1.3.6.1.2.1.2.2.1.2.1000009", "Port-Channel9",
-				".1.3.6.1.2.1.2.2.1.2.2002002", "Vlan2002",
-				".1.3.6.1.2.1.2.2.1.2.2002019", "Vlan2019",
-				".1.3.6.1.2.1.2.2.1.2.2002020", "Vlan2020",
-				".1.3.6.1.2.1.2.2.1.2.5000000", "Loopback0",
-				".1.3.6.1.2.1.2.2.1.14.8", uint(0),
-				".1.3.6.1.2.1.2.2.1.14.9", uint(226),
-				".1.3.6.1.2.1.2.2.1.14.10", uint(256),
-				".1.3.6.1.2.1.2.2.1.14.11", uint(296),
-				".1.3.6.1.2.1.4.20.1.1.10.100.192.2", "10.100.192.2",
-				".1.3.6.1.2.1.4.20.1.1.10.110.27.254", "10.110.27.254",
-				".1.3.6.1.2.1.4.20.1.1.66.208.216.74", "66.208.216.74",
-				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.97", "91 E2 BA E3 5A 61",
-				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.99", "53 54 00 5F 41 D0",
-				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.100", "53 54 00 4C 5A 5D",
-				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.102", "53 54 00 A9 A8 3B",
-				".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67.104", "53 54 00 5A A0 CA",
-				".1.3.6.1.2.1.25.2.3.1.2.1", ".1.3.6.1.2.1.25.2.1.2",
-				".1.3.6.1.2.1.25.2.3.1.2.2", ".1.3.6.1.2.1.25.2.1.2",
-				".1.3.6.1.2.1.25.2.3.1.2.3", ".1.3.6.1.2.1.25.2.1.2",
-				".1.3.6.1.2.1.25.2.3.1.2.4", ".1.3.6.1.2.1.25.2.1.9",
-				".1.3.6.1.2.1.31.1.1.1.6.46", uint64(1884401752869190),
-				".1.3.6.1.2.1.31.1.1.1.6.47", uint64(1883620653799494),
-				".1.3.6.1.2.1.31.1.1.1.6.48", uint64(1884283891426650),
-				".1.3.6.1.2.1.31.1.1.1.6.49001", uint64(2494191363092125),
-				".1.3.6.1.2.1.31.1.1.1.6.50001", uint64(17658827020872235),
-				".1.3.6.1.2.1.31.1.1.1.15.1000001", uint(100000),
-				".1.3.6.1.2.1.31.1.1.1.15.1000003", uint(60000),
-				".1.3.6.1.2.1.31.1.1.1.15.1000005", uint(80000),
-				".1.3.6.1.2.1.47.1.1.1.1.13.30", "4E 4D 2D 33 32 41 20 20 20 20 20 20 20 20 20 20 20 20 FF FF FF FF FF FF FF",
-				".1.3.6.1.6.3.10.2.1.3.0", 2937024,
-			},
-		},
-		result,
-	)
+				return walkResponse(oids, request.Variables[0].Name, 1), gosnmp.NoError
+			})
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			if test.limits != nil {
+				requester.SetTargetLimits(*test.limits)
+			}
+
+			apiRequest := apiRequest(walk(".1.2.1"), walk(".1.2.2"), walk(".1.2.3"), walk(".1.2.4"))
+			apiRequest.Host = host
+
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+			assert.NoError(err)
+			assert.Equal([][]any{{".1.2.1.1", 1}, {".1.2.2.1", 1}, {".1.2.3.1", 1}, {".1.2.4.1", 1}}, result.Varbinds)
+			assert.Equal(test.expectedInFlight, maxInFlight.Load())
+
+			families, err := registry.Gather()
+			assert.NoError(err)
+
+			var waitedPdus uint64
+
+			for _, family := range families {
+				if family.GetName() == "snmpproxy_target_wait_seconds" {
+					waitedPdus = family.GetMetric()[0].GetHistogram().GetSampleCount()
+				}
+			}
+
+			assert.Equal(test.expectedWaitedPdus, waitedPdus)
+		})
+	}
 }
 
-func TestWalkLastMibElement(t *testing.T) {
-	assert := require.New(t)
+func TestAdmissionLimits(t *testing.T) {
+	tests := []struct {
+		name             string
+		limits           snmpproxy.AdmissionLimits
+		expectedInFlight int32
+		expectedErr      error
+	}{
+		{
+			name:             "admitted",
+			limits:           snmpproxy.AdmissionLimits{MaxConcurrent: 2, MaxQueued: 2, MaxWait: time.Second},
+			expectedInFlight: 2,
+		},
+		{
+			name:             "queue full",
+			limits:           snmpproxy.AdmissionLimits{MaxConcurrent: 1, MaxQueued: 1, MaxWait: time.Second},
+			expectedInFlight: 1,
+			expectedErr:      snmpproxy.ErrQueueFull,
+		},
+		{
+			name:             "admission timeout",
+			limits:           snmpproxy.AdmissionLimits{MaxConcurrent: 1, MaxQueued: 3, MaxWait: time.Millisecond * 10},
+			expectedInFlight: 1,
+			expectedErr:      snmpproxy.ErrAdmissionTimeout,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
 
-	apiRequest := apiRequest(walk(".1.7"))
+			registry := prometheus.NewRegistry()
+			prometheus.DefaultRegisterer = registry
 
-	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			oids := []string{".1.2.1.1", ".1.2.2.1", ".1.2.3.1", ".1.2.4.1"}
 
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.NoError(err)
+			var inFlight, maxInFlight atomic.Int32
+
+			host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
 
-	assert.Equal([][]any{{".1.7.8.9", "Don't know what I'm"}}, result)
+				for {
+					if observed := maxInFlight.Load(); current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+						break
+					}
+				}
+
+				time.Sleep(time.Millisecond * 50)
+
+				return walkResponse(oids, request.Variables[0].Name, 1), gosnmp.NoError
+			})
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			requester.SetAdmissionLimits(test.limits)
+
+			// every walk is a separate SNMP operation
+			apiRequest := apiRequest(walk(".1.2.1"), walk(".1.2.2"), walk(".1.2.3"), walk(".1.2.4"))
+			apiRequest.Host = host
+
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+			if test.expectedErr == nil {
+				assert.NoError(err)
+				assert.Equal([][]any{{".1.2.1.1", 1}, {".1.2.2.1", 1}, {".1.2.3.1", 1}, {".1.2.4.1", 1}}, result.Varbinds)
+			} else {
+				assert.ErrorIs(err, test.expectedErr)
+
+				var admissionErr *snmpproxy.AdmissionError
+				assert.ErrorAs(err, &admissionErr)
+				assert.Equal(test.limits.MaxWait, admissionErr.RetryAfter)
+			}
+
+			assert.LessOrEqual(maxInFlight.Load(), test.expectedInFlight)
+
+			// all the slots are released
+			assert.Eventually(func() bool {
+				families, err := registry.Gather()
+				assert.NoError(err)
+
+				for _, family := range families {
+					if family.GetName() == "snmpproxy_admission_in_flight" {
+						return family.GetMetric()[0].GetGauge().GetValue() == 0
+					}
+				}
+
+				return false
+			}, time.Second, time.Millisecond)
+		})
+	}
 }
 
-func TestWalkLastMibElementAndSnmpVersion1(t *testing.T) {
+func TestAdmissionLimitsOfParallelWalk(t *testing.T) {
 	assert := require.New(t)
 
-	apiRequest := apiRequest(walk(".1.7"))
-	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	oids := []string{".1.2.3.1.1.1", ".1.2.3.1.1.2", ".1.2.3.1.2.1", ".1.2.3.1.2.2", ".1.2.3.1.3.1", ".1.2.3.1.3.2"}
+
+	host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+		time.Sleep(time.Millisecond * 20)
 
+		return walkResponse(oids, request.Variables[0].Name, 1), gosnmp.NoError
+	})
+
+	// the walk takes longer than MaxWait, so the second worker would time out if the walk held another slot
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	requester.SetAdmissionLimits(snmpproxy.AdmissionLimits{MaxConcurrent: 2, MaxQueued: 2, MaxWait: time.Millisecond * 50})
 
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.NoError(err)
+	request := walk(".1.2.3")
+	request.MaxRepetitions = 1
+	request.Parallel = 2
 
-	assert.Equal([][]any{{".1.7.8.9", "Don't know what I'm"}}, result)
+	apiRequest := apiRequest(request)
+	apiRequest.Host = host
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+	assert.Len(result.Varbinds[0], len(oids)*2)
 }
 
-func TestMultipleRequests(t *testing.T) {
+func TestAdmissionLimitsValidate(t *testing.T) {
 	assert := require.New(t)
 
-	apiRequest := apiRequest(
-		get([]string{".1.3.6.1.2.1.1.3.0"}),
-		walk(".1.7"),
-		getNext([]string{".1.3.6.1.2.1.2.2.1.14.9", ".1.3.6.1.2.1.2.2.1.14.10"}),
+	assert.NoError(snmpproxy.AdmissionLimits{}.Validate())
+	assert.NoError(snmpproxy.AdmissionLimits{MaxConcurrent: 1, MaxWait: time.Second}.Validate())
+	assert.EqualError(
+		snmpproxy.AdmissionLimits{MaxConcurrent: 1}.Validate(),
+		"MaxWait must be set if MaxConcurrent is, the operations could wait for each other forever",
 	)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	assert := require.New(t)
+
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	var down atomic.Bool
+
+	down.Store(true)
+
+	var received atomic.Int32
+
+	host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+		received.Add(1)
+
+		if down.Load() {
+			return nil, gosnmp.NoError
+		}
+
+		return []gosnmp.SnmpPDU{{Name: request.Variables[0].Name, Type: gosnmp.Integer, Value: 1}}, gosnmp.NoError
+	})
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	requester.SetCircuitBreaker(snmpproxy.CircuitBreakerSettings{MaxTimeouts: 2, CoolDown: time.Millisecond * 300})
 
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.NoError(err)
+	apiRequest := apiRequest(get([]string{".1.2.3"}))
+	apiRequest.Host = host
+	apiRequest.Timeout = time.Millisecond * 50
+
+	execute := func() error {
+		_, err := requester.ExecuteRequest(context.Background(), apiRequest)
+
+		return err
+	}
 
+	// two timeouts open the circuit
+	assert.ErrorIs(execute(), snmpproxy.ErrTimeout)
 	assert.Equal(
-		[][]any{
-			{".1.3.6.1.2.1.1.3.0", uint32(293718542)},
-			{".1.7.8.9", "Don't know what I'm"},
-			{
-				".1.3.6.1.2.1.2.2.1.14.10", uint(256),
-				".1.3.6.1.2.1.2.2.1.14.11", uint(296),
-			},
-		},
-		result,
+		map[string]snmpproxy.CircuitStatus{"127.0.0.1": {State: snmpproxy.CircuitClosed, Timeouts: 1}},
+		requester.CircuitBreaker().All(),
 	)
+	assert.ErrorIs(execute(), snmpproxy.ErrTimeout)
+	assert.EqualValues(2, received.Load())
+
+	err := execute()
+	assert.EqualError(err, "target unavailable: 127.0.0.1 (after 2 consecutive timeouts)")
+	assert.Equal(snmpproxy.ErrorCodeTargetUnavailable, snmpproxy.ErrorCode(err))
+	assert.EqualValues(2, received.Load())
+	assert.Equal(snmpproxy.CircuitOpen, requester.CircuitBreaker().All()["127.0.0.1"].State)
+
+	// the probe fails, so the circuit is open again
+	time.Sleep(time.Millisecond * 300)
+	assert.Equal(snmpproxy.CircuitHalfOpen, requester.CircuitBreaker().All()["127.0.0.1"].State)
+
+	assert.ErrorIs(execute(), snmpproxy.ErrTimeout)
+	assert.ErrorIs(execute(), snmpproxy.ErrTargetUnavailable)
+	assert.EqualValues(3, received.Load())
+	assert.Equal(snmpproxy.CircuitOpen, requester.CircuitBreaker().All()["127.0.0.1"].State)
+
+	// the probe succeeds, so the circuit is closed
+	down.Store(false)
+	time.Sleep(time.Millisecond * 300)
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+	assert.Equal([][]any{{".1.2.3", 1}}, result.Varbinds)
+	assert.Empty(requester.CircuitBreaker().All())
 }
 
-func TestMultipleRequestsWithSingleError(t *testing.T) {
+func TestCircuitBreakerForgetsStaleTargets(t *testing.T) {
 	assert := require.New(t)
 
-	apiRequest := apiRequest(
-		get([]string{".1.3.6.1.2.1.1.3.0"}),
-		walk(".1.7"),
-		getNext([]string{".1.3.6.1.2.1.2.2.1.14.9", ".1.7.9"}),
-	)
+	prometheus.DefaultRegisterer = prometheus.NewRegistry()
+
+	host := startAgent(t, func(*gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+		return nil, gosnmp.NoError
+	})
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	requester.SetCircuitBreaker(snmpproxy.CircuitBreakerSettings{MaxTimeouts: 2, CoolDown: time.Millisecond * 100})
 
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.Nil(result)
-	assert.EqualError(err, "end of mib: .1.7.9")
+	apiRequest := apiRequest(get([]string{".1.2.3"}))
+	apiRequest.Host = host
+	apiRequest.Timeout = time.Millisecond * 10
+
+	// the timeout isn't consecutive with the one a cool-down later
+	_, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.ErrorIs(err, snmpproxy.ErrTimeout)
+	assert.Len(requester.CircuitBreaker().All(), 1)
+
+	time.Sleep(time.Millisecond * 100)
+	assert.Empty(requester.CircuitBreaker().All())
+
+	// the half-open circuit is forgotten if nobody probes it for another cool-down
+	for range 2 {
+		_, err = requester.ExecuteRequest(context.Background(), apiRequest)
+		assert.ErrorIs(err, snmpproxy.ErrTimeout)
+	}
+
+	assert.Equal(snmpproxy.CircuitOpen, requester.CircuitBreaker().All()["127.0.0.1"].State)
+
+	time.Sleep(time.Millisecond * 100)
+	assert.Equal(snmpproxy.CircuitHalfOpen, requester.CircuitBreaker().All()["127.0.0.1"].State)
+
+	time.Sleep(time.Millisecond * 100)
+	assert.Empty(requester.CircuitBreaker().All())
 }
 
-func TestMultipleRequestsAllError(t *testing.T) {
+func TestConnectionPool(t *testing.T) {
 	assert := require.New(t)
 
-	apiRequest := apiRequest(
-		get([]string{".1.7.9"}),
-		walk(".1.7.9"),
-		getNext([]string{".1.3.6.1.2.1.2.2.1.14.9", ".1.7.9"}),
-	)
+	registry := prometheus.NewRegistry()
+	prometheus.DefaultRegisterer = registry
+
+	host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+		return []gosnmp.SnmpPDU{{Name: request.Variables[0].Name, Type: gosnmp.Integer, Value: 1}}, gosnmp.NoError
+	})
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+	requester.SetConnectionPool(snmpproxy.ConnectionPoolSettings{MaxIdle: 2, IdleTimeout: time.Millisecond * 100})
 
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.Nil(result)
-	assert.Error(err)
-	assert.Regexp(`(end of mib|no such instance): .1.7.9`, err.Error())
+	apiRequest := apiRequest(get([]string{".1.2.3"}))
+	apiRequest.Host = host
+
+	connections := func() map[string]float64 {
+		families, err := registry.Gather()
+		assert.NoError(err)
+
+		values := make(map[string]float64)
+
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				switch family.GetName() {
+				case "snmpproxy_pool_idle_connections":
+					values["idle"] = metric.GetGauge().GetValue()
+				case "snmpproxy_pool_connections_total":
+					values[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+				}
+			}
+		}
+
+		return values
+	}
+
+	for range 3 {
+		result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+		assert.NoError(err)
+		assert.Equal([][]any{{".1.2.3", 1}}, result.Varbinds)
+	}
+
+	assert.Equal(map[string]float64{"idle": 1, "created": 1, "reused": 2}, connections())
+
+	// the idle connection is closed
+	assert.Eventually(func() bool { return connections()["idle"] == 0 }, time.Second, time.Millisecond*10)
+
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+	assert.NoError(err)
+	assert.Equal([][]any{{".1.2.3", 1}}, result.Varbinds)
+	assert.Equal(map[string]float64{"idle": 1, "created": 2, "reused": 2}, connections())
 }
 
-func TestWalkWithTimeout(t *testing.T) {
-	assert := require.New(t)
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name             string
+		policy           snmpproxy.RetryPolicy
+		retries          uint8
+		expectedError    string
+		expectedDuration time.Duration
+	}{
+		{name: "fixed", retries: 2, expectedDuration: time.Millisecond * 100},
+		{
+			name:             "exponential",
+			policy:           snmpproxy.RetryPolicy{Backoff: snmpproxy.BackoffExponential},
+			retries:          2,
+			expectedDuration: time.Millisecond * 150,
+		},
+		{
+			name:             "exponential with max timeout",
+			policy:           snmpproxy.RetryPolicy{Backoff: snmpproxy.BackoffExponential, MaxTimeout: time.Millisecond * 60},
+			retries:          2,
+			expectedDuration: time.Millisecond * 110,
+		},
+		{name: "not enough retries", retries: 1, expectedError: "timeout: .1.2.3", expectedDuration: time.Millisecond * 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := require.New(t)
 
-	apiRequest := apiRequest(walk(".1.15"))
-	apiRequest.Host = "8.8.8.8"
-	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
-	apiRequest.Timeout = time.Millisecond
+			var received atomic.Int32
 
-	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
-	assert.Nil(result)
-	assert.EqualError(err, "timeout: .1.15")
+			// the agent responds to every third request only
+			host := startAgent(t, func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError) {
+				if received.Add(1)%3 != 0 {
+					return nil, gosnmp.NoError
+				}
+
+				return []gosnmp.SnmpPDU{{Name: request.Variables[0].Name, Type: gosnmp.Integer, Value: 1}}, gosnmp.NoError
+			})
+
+			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+			requester.SetRetryPolicies(snmpproxy.RetryPolicies{Profiles: map[string]snmpproxy.RetryPolicy{"p": test.policy}})
+
+			apiRequest := apiRequest(get([]string{".1.2.3"}))
+			apiRequest.Host = host
+			apiRequest.Profile = "p"
+			apiRequest.Timeout = time.Millisecond * 50
+			apiRequest.Retries = test.retries
+
+			start := time.Now()
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
+			duration := time.Since(start)
+
+			if test.expectedError == "" {
+				assert.NoError(err)
+				assert.Equal([][]any{{".1.2.3", 1}}, result.Varbinds)
+				assert.EqualValues(3, received.Load())
+			} else {
+				assert.EqualError(err, test.expectedError)
+				assert.EqualValues(2, received.Load())
+			}
+
+			// the timeouts of the attempts that got no response
+			assert.GreaterOrEqual(duration, test.expectedDuration)
+			assert.Less(duration, test.expectedDuration+time.Millisecond*50)
+		})
+	}
 }
 
 func TestWalkWithNoSuchInstanceError(t *testing.T) {
@@ -334,7 +1679,7 @@ func TestWalkWithNoSuchInstanceError(t *testing.T) {
 	apiRequest := apiRequest(walk(".1.3.5"))
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "no such instance: .1.3.5")
 }
@@ -346,7 +1691,7 @@ func TestWalkWithSnmpVersion1AndNoSuchInstanceError(t *testing.T) {
 	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "no such instance: .1.3.5")
 }
@@ -357,7 +1702,7 @@ func TestWalkWithEndOfMibError(t *testing.T) {
 	apiRequest := apiRequest(walk(".1.15"))
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "end of mib: .1.15")
 }
@@ -369,7 +1714,7 @@ func TestWalkWithSnmpVersion1AndEndOfMibError(t *testing.T) {
 	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "end of mib: .1.15")
 }
@@ -383,7 +1728,7 @@ func TestGetWithTimeout(t *testing.T) {
 	apiRequest.Timeout = time.Millisecond
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "timeout: .1.15")
 }
@@ -394,7 +1739,7 @@ func TestGetWithNoSuchInstanceError(t *testing.T) {
 	apiRequest := apiRequest(get([]string{".1.3.5"}))
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "no such instance: .1.3.5")
 }
@@ -406,7 +1751,7 @@ func TestGetWithSnmpVersion1AndNoSuchInstanceError(t *testing.T) {
 	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "no such instance: .1.3.5")
 }
@@ -418,7 +1763,7 @@ func TestGetWithMultipleOidsAndSnmpVersion1AndNoSuchInstanceError(t *testing.T)
 	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "no such instance: one of .1.3.5 1.3.2")
 }
@@ -429,7 +1774,7 @@ func TestGetNextWithEndOfMib(t *testing.T) {
 	apiRequest := apiRequest(getNext([]string{".1.15"}))
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "end of mib: .1.15")
 }
@@ -441,7 +1786,7 @@ func TestGetNextWithSnmpVersion1AndEndOfMib(t *testing.T) {
 	apiRequest.Version = snmpproxy.SnmpVersion(gosnmp.Version1)
 
 	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-	result, err := requester.ExecuteRequest(apiRequest)
+	result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 	assert.Nil(result)
 	assert.EqualError(err, "end of mib: .1.15")
 }
@@ -463,7 +1808,7 @@ func TestGetWithSnmpError(t *testing.T) {
 			apiRequest.Host = test.host
 
 			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-			result, err := requester.ExecuteRequest(apiRequest)
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 			assert.Nil(result)
 			assert.Error(err)
 			assert.Contains(err.Error(), "connection refused")
@@ -488,7 +1833,7 @@ func TestWalkWithSnmpError(t *testing.T) {
 			apiRequest.Host = test.host
 
 			requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-			result, err := requester.ExecuteRequest(apiRequest)
+			result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 			assert.Nil(result)
 			assert.Error(err)
 			assert.Contains(err.Error(), "connection refused")
@@ -532,7 +1877,7 @@ func TestInvalidHost(t *testing.T) {
 				apiRequest.Host = test.host
 
 				requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
-				result, err := requester.ExecuteRequest(apiRequest)
+				result, err := requester.ExecuteRequest(context.Background(), apiRequest)
 				assert.Nil(result)
 				assert.Error(err)
 				assert.Contains(err.Error(), test.err)
@@ -541,6 +1886,93 @@ func TestInvalidHost(t *testing.T) {
 	}
 }
 
+// walkResponse returns the varbinds following the OID, as returned by an agent that has only the given OIDs.
+func walkResponse(oids []string, oid string, repetitions int) []gosnmp.SnmpPDU {
+	dataUnits := make([]gosnmp.SnmpPDU, 0, repetitions)
+
+	start := slices.IndexFunc(oids, func(next string) bool {
+		return slices.Compare(oidSubIds(next), oidSubIds(oid)) > 0
+	})
+	if start == -1 {
+		start = len(oids)
+	}
+
+	for _, next := range oids[start:] {
+		dataUnits = append(dataUnits, gosnmp.SnmpPDU{Name: next, Type: gosnmp.Integer, Value: 1})
+	}
+
+	if len(dataUnits) < repetitions {
+		return append(dataUnits, gosnmp.SnmpPDU{Name: ".1.2.4", Type: gosnmp.EndOfMibView})
+	}
+
+	return dataUnits[:repetitions]
+}
+
+func oidSubIds(oid string) []int {
+	var subIds []int
+
+	for _, subId := range strings.Split(strings.TrimPrefix(oid, "."), ".") {
+		n, _ := strconv.Atoi(subId)
+		subIds = append(subIds, n)
+	}
+
+	return subIds
+}
+
+// startAgent starts a fake SNMP agent that responds to every request with the varbinds and the error status returned
+// by the function, which is called concurrently. If the function returns nil varbinds, the agent doesn't respond.
+func startAgent(t *testing.T, respond func(request *gosnmp.SnmpPacket) ([]gosnmp.SnmpPDU, gosnmp.SNMPError)) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	go func() {
+		buffer := make([]byte, 65535)
+		decoder := &gosnmp.GoSNMP{}
+
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			request, err := decoder.SnmpDecodePacket(buffer[:n])
+			if err != nil {
+				continue
+			}
+
+			// the requests are handled concurrently, as a real agent would do
+			go func() {
+				variables, errorStatus := respond(request)
+				if variables == nil {
+					return
+				}
+
+				response := &gosnmp.SnmpPacket{
+					Version:   request.Version,
+					Community: request.Community,
+					PDUType:   gosnmp.GetResponse,
+					RequestID: request.RequestID,
+					Error:     errorStatus,
+					Variables: variables,
+				}
+
+				out, err := response.MarshalMsg()
+				if err != nil {
+					panic(err)
+				}
+
+				_, _ = conn.WriteTo(out, addr)
+			}()
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
 func apiRequest(requests ...snmpproxy.Request) *snmpproxy.ApiRequest {
 	return &snmpproxy.ApiRequest{
 		Host:      "127.0.0.1:15728",
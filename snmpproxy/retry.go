@@ -0,0 +1,151 @@
+package snmpproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Backoff decides how the timeout of a request changes with every retry.
+type Backoff string
+
+const (
+	// BackoffFixed uses the same timeout for all the retries.
+	BackoffFixed = Backoff("fixed")
+	// BackoffExponential doubles the timeout with every retry, up to RetryPolicy.MaxTimeout (if set).
+	BackoffExponential = Backoff("exponential")
+)
+
+// RetryPolicy decides the timeouts of the retries of the requests. The number of retries is set by the API request.
+type RetryPolicy struct {
+	Backoff    Backoff       `json:"backoff"`     // BackoffFixed by default
+	MaxTimeout time.Duration `json:"max_timeout"` // caps BackoffExponential, see unmarshalDuration for JSON
+	// Jitter randomly changes every timeout by up to this fraction of it (0 to 1), so that the retries of the requests
+	// that timed out at once don't hit the device at once again.
+	Jitter float64 `json:"jitter"`
+}
+
+func (p *RetryPolicy) UnmarshalJSON(data []byte) error {
+	type tmp RetryPolicy
+
+	var t struct {
+		tmp
+		MaxTimeout json.RawMessage `json:"max_timeout"`
+	}
+
+	if err := json.Unmarshal(data, &t); err != nil {
+		return fmt.Errorf("failed to unmarshal RetryPolicy struct, got %+v: %w", string(data), err)
+	}
+
+	if t.MaxTimeout != nil {
+		maxTimeout, err := unmarshalDuration(t.MaxTimeout)
+		if err != nil {
+			return fmt.Errorf("field max_timeout %w", err)
+		}
+
+		t.tmp.MaxTimeout = maxTimeout
+	}
+
+	*p = RetryPolicy(t.tmp)
+
+	return nil
+}
+
+// Validate checks that the policy makes sense.
+func (p RetryPolicy) Validate() error {
+	switch p.Backoff {
+	case "", BackoffFixed, BackoffExponential:
+	default:
+		return fmt.Errorf("unknown backoff \"%s\", supported are: %s, %s", p.Backoff, BackoffFixed, BackoffExponential)
+	}
+
+	if p.MaxTimeout < 0 {
+		return errors.New("max_timeout mustn't be negative")
+	}
+
+	if p.Jitter < 0 || p.Jitter >= 1 {
+		return fmt.Errorf("jitter must be at least 0 and less than 1, got %g", p.Jitter)
+	}
+
+	return nil
+}
+
+// timeout returns the timeout of the attempt (zero is the first one, not a retry).
+func (p RetryPolicy) timeout(timeout time.Duration, attempt int) time.Duration {
+	timeout = p.backoff(timeout, attempt)
+
+	if p.Jitter != 0 {
+		timeout = time.Duration(float64(timeout) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+
+	return max(timeout, time.Millisecond)
+}
+
+func (p RetryPolicy) backoff(timeout time.Duration, attempt int) time.Duration {
+	if p.Backoff != BackoffExponential {
+		return timeout
+	}
+
+	for ; attempt > 0 && timeout < math.MaxInt64/2; attempt-- {
+		timeout *= 2
+	}
+
+	if p.MaxTimeout != 0 {
+		timeout = min(timeout, p.MaxTimeout)
+	}
+
+	return timeout
+}
+
+// worstCase returns the longest possible duration of a request with all its retries.
+func (p RetryPolicy) worstCase(timeout time.Duration, retries uint8) time.Duration {
+	var total float64
+
+	for attempt := range int(retries) + 1 {
+		total += float64(p.backoff(timeout, attempt)) * (1 + p.Jitter)
+	}
+
+	if total >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+
+	return time.Duration(total)
+}
+
+// RetryPolicies hold the default RetryPolicy, and those of the device profiles (see ApiRequest.Profile).
+type RetryPolicies struct {
+	Default  RetryPolicy
+	Profiles map[string]RetryPolicy // by the lowercase names, the profiles are case-insensitive (as are the config keys)
+}
+
+// Validate checks all the policies.
+func (p RetryPolicies) Validate() error {
+	if err := p.Default.Validate(); err != nil {
+		return fmt.Errorf("default retry policy: %w", err)
+	}
+
+	for profile, policy := range p.Profiles {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("retry policy of profile %s: %w", profile, err)
+		}
+	}
+
+	return nil
+}
+
+// get returns the policy used by the API request: its own one, or the one of its device profile, or the default one.
+func (p RetryPolicies) get(apiRequest *ApiRequest) RetryPolicy {
+	if apiRequest.RetryPolicy != nil {
+		return *apiRequest.RetryPolicy
+	}
+
+	if policy, ok := p.Profiles[strings.ToLower(apiRequest.Profile)]; ok {
+		return policy
+	}
+
+	return p.Default
+}
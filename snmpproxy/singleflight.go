@@ -0,0 +1,226 @@
+package snmpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// IndependentRequester is a Requester that can execute the requests of an ApiRequest independently of each other,
+// see SingleFlightRequester.
+type IndependentRequester interface {
+	Requester
+	// ExecuteIndependently is like ExecuteRequest, except that the requests don't fail because of each other. It
+	// returns the error of every request instead, the results of the failed requests are kept only if they are partial.
+	ExecuteIndependently(ctx context.Context, apiRequest *ApiRequest) (*Result, []error)
+	// PartialResultPolicy decides whether the results are returned together with the errors of the requests.
+	PartialResultPolicy() PartialResultPolicy
+}
+
+// flightCall is a single request being executed on behalf of all the API requests that contain it.
+type flightCall struct {
+	key    string
+	batch  *flightBatch
+	done   chan struct{}
+	result []any
+	cursor *WalkCursor
+	err    error
+}
+
+// flightBatch holds the requests of an API request that weren't being executed already, they are executed together.
+// The execution is cancelled once all the API requests waiting for any of them are gone.
+type flightBatch struct {
+	calls  []*flightCall
+	cancel context.CancelFunc
+	users  int
+}
+
+// SingleFlightRequester shares the execution of the identical requests of the concurrent API requests, e.g. when
+// multiple clients walk the same table of the same device at once. The requests are identical if they are sent
+// to the same host with the same credentials, timeout, retries (and their policy) and deadline, and all their fields
+// are the same. The shared requests are executed independently, so that every API request gets only the errors of its
+// own requests. Nothing is kept once the execution finishes, and the streamed requests aren't shared at all.
+type SingleFlightRequester struct {
+	requester IndependentRequester
+	mu        sync.Mutex
+	calls     map[string]*flightCall
+}
+
+func (r *SingleFlightRequester) ExecuteRequest(ctx context.Context, apiRequest *ApiRequest) (*Result, error) {
+	calls := make([]*flightCall, len(apiRequest.Requests))
+	batch := &flightBatch{}
+	subRequest := *apiRequest
+	subRequest.Requests = nil
+
+	r.mu.Lock()
+
+	for requestNo, request := range apiRequest.Requests {
+		key := r.key(apiRequest, request)
+
+		call, ok := r.calls[key]
+		if !ok {
+			call = &flightCall{key: key, batch: batch, done: make(chan struct{})}
+			r.calls[key] = call
+
+			batch.calls = append(batch.calls, call)
+			subRequest.Requests = append(subRequest.Requests, request)
+		}
+
+		calls[requestNo] = call
+	}
+
+	if len(batch.calls) != 0 {
+		var batchCtx context.Context
+
+		// the batch may outlive this API request, if other ones wait for it
+		batchCtx, batch.cancel = context.WithCancel(context.WithoutCancel(ctx))
+
+		go r.execute(batchCtx, batch, &subRequest)
+	}
+
+	// the API request keeps the batches of all its calls running until it has their results or it's gone
+	waiting := make(map[*flightBatch]int)
+	stops := make(map[*flightBatch]func() bool)
+
+	for _, call := range calls {
+		if waiting[call.batch]++; waiting[call.batch] != 1 {
+			continue
+		}
+
+		call.batch.users++
+
+		stops[call.batch] = context.AfterFunc(ctx, func() { r.leave(call.batch) })
+	}
+
+	r.mu.Unlock()
+
+	result := &Result{Varbinds: make([][]any, len(calls))}
+
+	var (
+		err     error
+		partial bool // the failed requests have results too, e.g. the incomplete walks
+	)
+
+	for requestNo, call := range calls {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+
+		if waiting[call.batch]--; waiting[call.batch] == 0 && stops[call.batch]() {
+			r.leave(call.batch)
+		}
+
+		result.Varbinds[requestNo] = call.result
+
+		if call.cursor != nil {
+			if result.Cursors == nil {
+				result.Cursors = make([]*WalkCursor, len(calls))
+			}
+
+			result.Cursors[requestNo] = call.cursor
+		}
+
+		if call.err == nil {
+			continue
+		}
+
+		partial = partial || call.result != nil || call.cursor != nil
+
+		if err == nil {
+			err = call.err
+		}
+	}
+
+	if err != nil && !r.requester.PartialResultPolicy().keepsResults(err, partial) {
+		return nil, err
+	}
+
+	return result, err
+}
+
+func (r *SingleFlightRequester) StreamRequest(
+	ctx context.Context,
+	apiRequest *ApiRequest,
+	fn VarbindFunc,
+) ([]*WalkCursor, error) {
+	return r.requester.StreamRequest(ctx, apiRequest, fn)
+}
+
+// execute executes the requests of the batch and passes the results to all the API requests waiting for them.
+func (r *SingleFlightRequester) execute(ctx context.Context, batch *flightBatch, apiRequest *ApiRequest) {
+	defer batch.cancel()
+
+	result, errs := r.requester.ExecuteIndependently(ctx, apiRequest)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, call := range batch.calls {
+		r.finish(call, result, i, errs[i])
+	}
+}
+
+// finish passes the result of the request (its index in the result) and the error to the waiting API requests.
+func (r *SingleFlightRequester) finish(call *flightCall, result *Result, i int, err error) {
+	if result != nil {
+		call.result = result.Varbinds[i]
+
+		if result.Cursors != nil {
+			call.cursor = result.Cursors[i]
+		}
+	}
+
+	call.err = err
+
+	r.forget(call)
+	close(call.done)
+}
+
+// leave is called when an API request waiting for the batch is gone.
+func (r *SingleFlightRequester) leave(batch *flightBatch) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if batch.users--; batch.users != 0 {
+		return
+	}
+
+	// nobody is interested in the results anymore, the following API requests must not wait for the cancelled batch
+	for _, call := range batch.calls {
+		r.forget(call)
+	}
+
+	batch.cancel()
+}
+
+func (r *SingleFlightRequester) forget(call *flightCall) {
+	if r.calls[call.key] == call {
+		delete(r.calls, call.key)
+	}
+}
+
+func (*SingleFlightRequester) key(apiRequest *ApiRequest, request Request) string {
+	key, err := json.Marshal([]any{
+		apiRequest.Host,
+		apiRequest.Community,
+		apiRequest.Version,
+		apiRequest.Retries,
+		apiRequest.Timeout,
+		apiRequest.Deadline,
+		apiRequest.Profile,
+		apiRequest.RetryPolicy,
+		request,
+		request.AutoMaxRepetitions,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return string(key)
+}
+
+func NewSingleFlightRequester(requester IndependentRequester) *SingleFlightRequester {
+	return &SingleFlightRequester{requester: requester, calls: make(map[string]*flightCall)}
+}
@@ -0,0 +1,177 @@
+package snmpproxy_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleFlightRequester(t *testing.T) {
+	assert := require.New(t)
+
+	ifTable := snmpproxy.Request{RequestType: snmpproxy.Walk, Oids: []string{".1.3.6.1.2.1.2.2"}}
+	sysDescr := snmpproxy.Request{RequestType: snmpproxy.Get, Oids: []string{".1.3.6.1.2.1.1.1.0"}}
+	sysName := snmpproxy.Request{RequestType: snmpproxy.Get, Oids: []string{".1.3.6.1.2.1.1.5.0"}}
+
+	apiRequest := func(requests ...snmpproxy.Request) *snmpproxy.ApiRequest {
+		return &snmpproxy.ApiRequest{Host: "localhost", Community: "public", Requests: requests}
+	}
+
+	expectRequests := func(requests ...snmpproxy.Request) any {
+		return mock.MatchedBy(func(apiRequest *snmpproxy.ApiRequest) bool {
+			return reflect.DeepEqual(requests, apiRequest.Requests)
+		})
+	}
+
+	type executed struct {
+		result *snmpproxy.Result
+		err    error
+	}
+
+	execute := func(ctx context.Context, requester snmpproxy.Requester, apiRequest *snmpproxy.ApiRequest) <-chan executed {
+		responses := make(chan executed, 1)
+
+		go func() {
+			result, err := requester.ExecuteRequest(ctx, apiRequest)
+			responses <- executed{result: result, err: err}
+		}()
+
+		return responses
+	}
+
+	started := make(chan context.Context)
+	unblock := make(chan struct{})
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	blocked := requester.On("ExecuteIndependently", mock.Anything, expectRequests(ifTable, sysDescr)).Twice()
+	blocked.Run(func(args mock.Arguments) {
+		started <- args.Get(0).(context.Context)
+		<-unblock
+	}).Return(
+		&snmpproxy.Result{Varbinds: [][]any{{".1.3.6.1.2.1.2.2.1.1.1", 1}, {".1.3.6.1.2.1.1.1.0", "a"}}},
+		[]error{nil, nil},
+	)
+	requester.On("ExecuteIndependently", mock.Anything, expectRequests(sysName)).Twice().Run(func(mock.Arguments) {
+		started <- nil
+	}).Return(&snmpproxy.Result{Varbinds: [][]any{{".1.3.6.1.2.1.1.5.0", "b"}}}, []error{nil})
+
+	singleFlightRequester := snmpproxy.NewSingleFlightRequester(requester)
+
+	// the second API request waits for the walk of the first one
+	first := execute(context.Background(), singleFlightRequester, apiRequest(ifTable, sysDescr))
+	<-started
+
+	second := execute(context.Background(), singleFlightRequester, apiRequest(sysName, ifTable))
+	<-started
+
+	unblock <- struct{}{}
+
+	response := <-first
+	assert.NoError(response.err)
+	assert.Equal([][]any{{".1.3.6.1.2.1.2.2.1.1.1", 1}, {".1.3.6.1.2.1.1.1.0", "a"}}, response.result.Varbinds)
+
+	response = <-second
+	assert.NoError(response.err)
+	assert.Equal([][]any{{".1.3.6.1.2.1.1.5.0", "b"}, {".1.3.6.1.2.1.2.2.1.1.1", 1}}, response.result.Varbinds)
+
+	// the shared execution continues when the API request that started it is gone
+	ctx, cancel := context.WithCancel(context.Background())
+
+	first = execute(ctx, singleFlightRequester, apiRequest(ifTable, sysDescr))
+	batchCtx := <-started
+
+	second = execute(context.Background(), singleFlightRequester, apiRequest(ifTable, sysName))
+	<-started
+
+	cancel()
+
+	response = <-first
+	assert.ErrorIs(response.err, context.Canceled)
+	assert.NoError(batchCtx.Err())
+
+	unblock <- struct{}{}
+
+	response = <-second
+	assert.NoError(response.err)
+	assert.Equal([][]any{{".1.3.6.1.2.1.2.2.1.1.1", 1}, {".1.3.6.1.2.1.1.5.0", "b"}}, response.result.Varbinds)
+}
+
+func TestSingleFlightRequesterErrorOfOtherRequest(t *testing.T) {
+	assert := require.New(t)
+
+	ifTable := snmpproxy.Request{RequestType: snmpproxy.Walk, Oids: []string{".1.3.6.1.2.1.2.2"}}
+	sysDescr := snmpproxy.Request{RequestType: snmpproxy.Get, Oids: []string{".1.3.6.1.2.1.1.1.0"}}
+	sysName := snmpproxy.Request{RequestType: snmpproxy.Get, Oids: []string{".1.3.6.1.2.1.1.5.0"}}
+
+	apiRequest := func(requests ...snmpproxy.Request) *snmpproxy.ApiRequest {
+		return &snmpproxy.ApiRequest{Host: "localhost", Community: "public", Requests: requests}
+	}
+
+	expectRequests := func(requests ...snmpproxy.Request) any {
+		return mock.MatchedBy(func(apiRequest *snmpproxy.ApiRequest) bool {
+			return reflect.DeepEqual(requests, apiRequest.Requests)
+		})
+	}
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+
+	requester := &mockRequester{}
+	defer requester.AssertExpectations(t)
+
+	// only the failed request fails, the other one isn't executed again for the second API request
+	requester.On("ExecuteIndependently", mock.Anything, expectRequests(sysDescr, sysName)).Once().
+		Run(func(mock.Arguments) {
+			started <- struct{}{}
+			<-unblock
+		}).
+		Return(
+			&snmpproxy.Result{Varbinds: [][]any{{".1.3.6.1.2.1.1.1.0", "a"}, nil}},
+			[]error{nil, errors.New("no such object: .1.3.6.1.2.1.1.5.0")},
+		)
+	requester.On("ExecuteIndependently", mock.Anything, expectRequests(ifTable)).Once().Run(func(mock.Arguments) {
+		started <- struct{}{}
+	}).Return(&snmpproxy.Result{Varbinds: [][]any{{".1.3.6.1.2.1.2.2.1.1.1", 1}}}, []error{nil})
+
+	singleFlightRequester := snmpproxy.NewSingleFlightRequester(requester)
+
+	type executed struct {
+		result *snmpproxy.Result
+		err    error
+	}
+
+	first := make(chan executed, 1)
+
+	go func() {
+		result, err := singleFlightRequester.ExecuteRequest(context.Background(), apiRequest(sysDescr, sysName))
+		first <- executed{result: result, err: err}
+	}()
+
+	<-started
+
+	second := make(chan executed, 1)
+
+	go func() {
+		result, err := singleFlightRequester.ExecuteRequest(context.Background(), apiRequest(ifTable, sysDescr))
+		second <- executed{result: result, err: err}
+	}()
+
+	<-started
+
+	close(unblock)
+
+	response := <-first
+	assert.EqualError(response.err, "no such object: .1.3.6.1.2.1.1.5.0")
+	assert.Nil(response.result)
+
+	response = <-second
+	assert.NoError(response.err)
+	assert.Equal([][]any{{".1.3.6.1.2.1.2.2.1.1.1", 1}, {".1.3.6.1.2.1.1.1.0", "a"}}, response.result.Varbinds)
+}
@@ -0,0 +1,163 @@
+package snmpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WalkStrategy decides which SNMP operations are used to walk a subtree with SNMP v2c (v1 always uses GetNext).
+type WalkStrategy string
+
+const (
+	// WalkStrategyBulk uses GetBulk with the requested max repetitions.
+	WalkStrategyBulk = WalkStrategy("bulk")
+	// WalkStrategyGetNext uses GetNext, one varbind per request.
+	WalkStrategyGetNext = WalkStrategy("getnext")
+	// WalkStrategyAuto starts with GetBulk, and if the walk fails, it continues with lower max repetitions
+	// and eventually with GetNext. What worked is remembered for the host, see WalkMemory.
+	WalkStrategyAuto = WalkStrategy("auto")
+)
+
+const (
+	// walkMemoryTtl is how long the learned settings of an idle host are kept, so that the devices that got fixed
+	// (e.g. by a firmware upgrade) get a chance to use GetBulk again.
+	walkMemoryTtl = time.Hour
+	// minFallbackMaxRepetitions is the lowest max repetitions WalkStrategyAuto falls back to before using GetNext.
+	minFallbackMaxRepetitions = 5
+)
+
+func NewWalkStrategy(strategy string) (WalkStrategy, error) {
+	switch WalkStrategy(strategy) {
+	case "":
+		return WalkStrategyBulk, nil
+	case WalkStrategyBulk, WalkStrategyGetNext, WalkStrategyAuto:
+		return WalkStrategy(strategy), nil
+	default:
+		return "", fmt.Errorf(
+			"unknown walk strategy \"%s\", supported are: %s, %s, %s",
+			strategy,
+			WalkStrategyBulk,
+			WalkStrategyGetNext,
+			WalkStrategyAuto,
+		)
+	}
+}
+
+// walkSettings are the parameters of the SNMP operations used by a walk.
+type walkSettings struct {
+	getNext        bool
+	maxRepetitions uint32            // only used without getNext
+	tuner          *repetitionsTuner // optional, adjusts the max repetitions during the walk
+}
+
+// fallback returns the less demanding settings to use after the walk failed: lower max repetitions first,
+// then GetNext. It returns false if there is nothing left to fall back to.
+func (s walkSettings) fallback() (walkSettings, bool) {
+	switch {
+	case s.getNext:
+		return s, false
+	case s.maxRepetitions/4 >= minFallbackMaxRepetitions:
+		s.maxRepetitions /= 4
+	default:
+		s.getNext = true
+	}
+
+	return s, true
+}
+
+// LearnedWalkSettings are the settings of the walks learned for a host.
+type LearnedWalkSettings struct {
+	// GetNext and MaxRepetitions made the walks with WalkStrategyAuto succeed (MaxRepetitions is zero
+	// if there was no need to lower it).
+	GetNext        bool   `json:"getnext"`
+	MaxRepetitions uint32 `json:"max_repetitions,omitempty"`
+	// AutoMaxRepetitions is used by the walks with max_repetitions "auto", see repetitionsTuner.
+	AutoMaxRepetitions uint32    `json:"auto_max_repetitions,omitempty"`
+	Expires            time.Time `json:"expires"`
+}
+
+// WalkMemory remembers per host the settings learned by the walks. It also serves them as JSON over HTTP.
+type WalkMemory struct {
+	mu    sync.Mutex
+	hosts map[string]LearnedWalkSettings
+}
+
+func (m *WalkMemory) Get(host string) (LearnedWalkSettings, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	learned, ok := m.hosts[host]
+	if !ok || time.Now().After(learned.Expires) {
+		return LearnedWalkSettings{}, false
+	}
+
+	return learned, true
+}
+
+// All returns the learned settings of all the hosts.
+func (m *WalkMemory) All() map[string]LearnedWalkSettings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeExpired(time.Now())
+
+	hosts := make(map[string]LearnedWalkSettings, len(m.hosts))
+	for host, learned := range m.hosts {
+		hosts[host] = learned
+	}
+
+	return hosts
+}
+
+func (m *WalkMemory) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != "GET" {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	b, err := json.Marshal(m.All())
+	if err != nil {
+		panic(err)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write(b)
+}
+
+// update changes the learned settings of the host, and keeps them for another walkMemoryTtl.
+func (m *WalkMemory) update(host string, fn func(learned *LearnedWalkSettings)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+
+	learned, ok := m.hosts[host]
+	if !ok || now.After(learned.Expires) {
+		learned = LearnedWalkSettings{}
+
+		// new hosts are rare, so it's cheap to clean up here
+		m.removeExpired(now)
+	}
+
+	fn(&learned)
+
+	learned.Expires = now.Add(walkMemoryTtl)
+	m.hosts[host] = learned
+}
+
+func (m *WalkMemory) removeExpired(now time.Time) {
+	for host, learned := range m.hosts {
+		if now.After(learned.Expires) {
+			delete(m.hosts, host)
+		}
+	}
+}
+
+func NewWalkMemory() *WalkMemory {
+	return &WalkMemory{hosts: make(map[string]LearnedWalkSettings)}
+}
@@ -0,0 +1,70 @@
+package snmpproxy_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grongor/go-snmp-proxy/snmpproxy"
+	"github.com/grongor/go-snmp-proxy/snmpproxy/mib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWalkStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		expected snmpproxy.WalkStrategy
+		err      string
+	}{
+		{strategy: "", expected: snmpproxy.WalkStrategyBulk},
+		{strategy: "bulk", expected: snmpproxy.WalkStrategyBulk},
+		{strategy: "getnext", expected: snmpproxy.WalkStrategyGetNext},
+		{strategy: "auto", expected: snmpproxy.WalkStrategyAuto},
+		{strategy: "getNext", err: `unknown walk strategy "getNext", supported are: bulk, getnext, auto`},
+	}
+	for _, test := range tests {
+		t.Run(test.strategy, func(t *testing.T) {
+			strategy, err := snmpproxy.NewWalkStrategy(test.strategy)
+
+			if test.err == "" {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, strategy)
+			} else {
+				require.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestWalkMemoryServeHTTP(t *testing.T) {
+	assert := require.New(t)
+
+	requester := snmpproxy.NewGosnmpRequester(snmpproxy.NewValueFormatter(mib.NewDataProvider(nil)))
+
+	request := walk(".1.3.6.1.2.1.31.1.1.1.15")
+	request.AutoMaxRepetitions = true
+
+	_, err := requester.ExecuteRequest(context.Background(), apiRequest(request))
+	assert.NoError(err)
+
+	learned, ok := requester.WalkMemory().Get("127.0.0.1:15728")
+	assert.True(ok)
+
+	recorder := httptest.NewRecorder()
+	requester.WalkMemory().ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/walk-settings", nil))
+
+	expires, err := learned.Expires.MarshalJSON()
+	assert.NoError(err)
+
+	response := recorder.Result()
+	assert.Equal(http.StatusOK, response.StatusCode)
+	assert.Equal(
+		`{"127.0.0.1:15728":{"getnext":false,"auto_max_repetitions":20,"expires":`+string(expires)+`}}`,
+		read(response.Body),
+	)
+
+	recorder = httptest.NewRecorder()
+	requester.WalkMemory().ServeHTTP(recorder, httptest.NewRequest("POST", "/admin/walk-settings", nil))
+	assert.Equal(http.StatusMethodNotAllowed, recorder.Result().StatusCode)
+}
@@ -0,0 +1,98 @@
+package snmpproxy
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// TargetLimits limit the PDUs sent to a single target (host) at once, shared by all the API requests. Some devices
+// drop the packets, or even restart their SNMP agent, when they get too many requests at once.
+type TargetLimits struct {
+	MaxInFlight uint     // zero means unlimited
+	Sequential  []string // hosts that get only a single PDU at a time
+}
+
+// targetLimiter holds the in-flight PDUs of the targets, see TargetLimits.
+type targetLimiter struct {
+	limits   TargetLimits
+	waitTime prometheus.Histogram
+	mu       sync.Mutex
+	targets  map[string]*targetSlots
+}
+
+type targetSlots struct {
+	slots chan struct{}
+	users int // the slots are removed once nobody uses them
+}
+
+// acquire waits for a free slot of the target, unless the context is done first. It returns the function that
+// releases the slot.
+func (l *targetLimiter) acquire(ctx context.Context, target string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	size := l.limits.MaxInFlight
+	if slices.Contains(l.limits.Sequential, target) {
+		size = 1
+	}
+
+	if size == 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+
+	slots, ok := l.targets[target]
+	if !ok {
+		slots = &targetSlots{slots: make(chan struct{}, size)}
+		l.targets[target] = slots
+	}
+
+	slots.users++
+
+	l.mu.Unlock()
+
+	start := time.Now()
+
+	select {
+	case slots.slots <- struct{}{}:
+		l.waitTime.Observe(time.Since(start).Seconds())
+
+		return func() {
+			<-slots.slots
+			l.release(target, slots)
+		}, nil
+	case <-ctx.Done():
+		l.release(target, slots)
+
+		return nil, context.Cause(ctx)
+	}
+}
+
+func (l *targetLimiter) release(target string, slots *targetSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slots.users--; slots.users == 0 {
+		delete(l.targets, target)
+	}
+}
+
+func newTargetLimiter(limits TargetLimits) *targetLimiter {
+	return &targetLimiter{
+		limits:  limits,
+		targets: make(map[string]*targetSlots),
+		waitTime: promauto.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "snmpproxy",
+			Name:      "target_wait_seconds",
+			Help:      "Time the PDUs spent waiting for a free slot of their target",
+			Buckets:   []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 30},
+		}),
+	}
+}
@@ -1,29 +1,47 @@
 package snmpproxy
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
 
+var (
+	errOidWithoutDot = errors.New("must begin with a dot")
+	errOidNotNumeric = errors.New("must consist of numbers separated by dots")
+)
+
+// defaultMinTimeout is the minimum allowed timeout, unless SetMinTimeout sets a higher one.
+const defaultMinTimeout = 10 * time.Millisecond
+
 type RequestValidator struct {
-	maxTimeout time.Duration
-	maxRetries uint8
+	minTimeout         time.Duration
+	maxTimeout         time.Duration
+	maxRetries         uint8
+	maxWalkParallelism uint8
+	maxDuration        time.Duration // of a request with all its retries
+	retryPolicies      RetryPolicies
 }
 
 func (v *RequestValidator) Validate(apiRequest *ApiRequest) error { //nolint:cyclop // No need to split this
+	if apiRequest.Timeout < v.minTimeout {
+		return fmt.Errorf("minimum allowed timeout is %s, got %s", v.minTimeout, apiRequest.Timeout)
+	}
+
 	if apiRequest.Timeout > v.maxTimeout {
-		return fmt.Errorf(
-			"maximum allowed timeout is %d seconds, got %d seconds",
-			v.maxTimeout/time.Second,
-			apiRequest.Timeout/time.Second,
-		)
+		return fmt.Errorf("maximum allowed timeout is %s, got %s", v.maxTimeout, apiRequest.Timeout)
 	}
 
 	if apiRequest.Retries > v.maxRetries {
 		return fmt.Errorf("maximum allowed number of retries is %d, got %d", v.maxRetries, apiRequest.Retries)
 	}
 
+	if err := v.validateRetries(apiRequest); err != nil {
+		return err
+	}
+
 	if len(apiRequest.Requests) == 0 {
 		return fmt.Errorf("at least one Request must be provided")
 	}
@@ -49,12 +67,54 @@ func (v *RequestValidator) Validate(apiRequest *ApiRequest) error { //nolint:cyc
 		}
 
 		for _, oid := range request.Oids {
-			if oid[0] != '.' {
-				return fmt.Errorf("request[%d]: all OIDs must begin with a dot, got: %s", i, oid)
+			if err := validateOid(oid); err != nil {
+				return fmt.Errorf("request[%d]: all OIDs %w, got: %s", i, err, oid)
 			}
 		}
 
-		if request.RequestType == Walk && request.MaxRepetitions == 0 {
+		if request.RequestType != Walk && (request.MaxResults != 0 || request.Cursor != nil) {
+			return fmt.Errorf("request[%d]: fields max_results and cursor are only supported with RequestType = Walk", i)
+		}
+
+		if request.RequestType != Walk && request.PartialResults {
+			return fmt.Errorf("request[%d]: field partial_results is only supported with RequestType = Walk", i)
+		}
+
+		if request.WalkStrategy != "" {
+			if request.RequestType != Walk {
+				return fmt.Errorf("request[%d]: field walk_strategy is only supported with RequestType = Walk", i)
+			}
+
+			if _, err := NewWalkStrategy(string(request.WalkStrategy)); err != nil {
+				return fmt.Errorf("request[%d]: %w", i, err)
+			}
+		}
+
+		if request.Parallel != 0 {
+			if request.RequestType != Walk {
+				return fmt.Errorf("request[%d]: field parallel is only supported with RequestType = Walk", i)
+			}
+
+			if request.Parallel > v.maxWalkParallelism {
+				return fmt.Errorf(
+					"request[%d]: maximum allowed parallelism is %d, got %d",
+					i,
+					v.maxWalkParallelism,
+					request.Parallel,
+				)
+			}
+		}
+
+		if request.Cursor != nil && request.Cursor.Root != request.Oids[0] {
+			return fmt.Errorf(
+				"request[%d]: cursor belongs to a walk of a different OID %s, expected %s",
+				i,
+				request.Cursor.Root,
+				request.Oids[0],
+			)
+		}
+
+		if request.RequestType == Walk && request.MaxRepetitions == 0 && !request.AutoMaxRepetitions {
 			return fmt.Errorf(
 				"request[%d]: field max_repetitions is required for RequestType = Walk, "+
 					"and it mustn't be zero, oid: %s",
@@ -67,6 +127,74 @@ func (v *RequestValidator) Validate(apiRequest *ApiRequest) error { //nolint:cyc
 	return nil
 }
 
+// validateRetries checks the retry policy of the request, and that none of its SNMP requests (including the retries)
+// may take longer than allowed.
+func (v *RequestValidator) validateRetries(apiRequest *ApiRequest) error {
+	if apiRequest.RetryPolicy != nil {
+		if err := apiRequest.RetryPolicy.Validate(); err != nil {
+			return fmt.Errorf("invalid retry_policy: %w", err)
+		}
+	}
+
+	policy := v.retryPolicies.get(apiRequest)
+
+	if worstCase := policy.worstCase(apiRequest.Timeout, apiRequest.Retries); worstCase > v.maxDuration {
+		return fmt.Errorf(
+			"a request with %d retries may take up to %s with the retry policy, maximum allowed is %s",
+			apiRequest.Retries,
+			worstCase,
+			v.maxDuration,
+		)
+	}
+
+	return nil
+}
+
+// SetRetryPolicies sets the retry policies the requests are validated with, they should be the same
+// as those of the Requester.
+func (v *RequestValidator) SetRetryPolicies(policies RetryPolicies) {
+	v.retryPolicies = policies
+}
+
+// SetMaxDuration sets the maximum duration of a single SNMP request with all its retries, as allowed by
+// the retry policy. It's the maximum timeout times the maximum retries plus one by default.
+func (v *RequestValidator) SetMaxDuration(maxDuration time.Duration) {
+	v.maxDuration = maxDuration
+}
+
+// SetMinTimeout sets the minimum allowed timeout, so that the agents aren't flooded by the retries. It can't be
+// lower than defaultMinTimeout, the lower values are ignored.
+func (v *RequestValidator) SetMinTimeout(minTimeout time.Duration) {
+	v.minTimeout = max(minTimeout, defaultMinTimeout)
+}
+
+// SetMaxWalkParallelism sets the maximum allowed value of the field parallel of the walks (zero disallows them).
+func (v *RequestValidator) SetMaxWalkParallelism(maxWalkParallelism uint8) {
+	v.maxWalkParallelism = maxWalkParallelism
+}
+
+// validateOid checks that the OID is numeric and begins with a dot, e.g. .1.3.6.1.2.1.
+func validateOid(oid string) error {
+	if !strings.HasPrefix(oid, ".") {
+		return errOidWithoutDot
+	}
+
+	for _, subId := range strings.Split(oid[1:], ".") {
+		if _, err := strconv.ParseUint(subId, 10, 32); err != nil {
+			return errOidNotNumeric
+		}
+	}
+
+	return nil
+}
+
 func NewRequestValidator(maxTimeoutSeconds uint, maxRetries uint8) *RequestValidator {
-	return &RequestValidator{maxTimeout: time.Duration(maxTimeoutSeconds) * time.Second, maxRetries: maxRetries}
+	maxTimeout := time.Duration(maxTimeoutSeconds) * time.Second
+
+	return &RequestValidator{
+		minTimeout:  defaultMinTimeout,
+		maxTimeout:  maxTimeout,
+		maxRetries:  maxRetries,
+		maxDuration: maxTimeout * (time.Duration(maxRetries) + 1),
+	}
 }
@@ -42,6 +42,18 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "no error, auto max repetitions",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType:        snmpproxy.Walk,
+						Oids:               []string{".7.8.9"},
+						AutoMaxRepetitions: true,
+					},
+				},
+			},
+		},
 		{
 			name: "too large timeout",
 			request: &snmpproxy.ApiRequest{
@@ -53,7 +65,7 @@ func TestValidate(t *testing.T) {
 					},
 				},
 			},
-			err: "maximum allowed timeout is 10 seconds, got 100 seconds",
+			err: "maximum allowed timeout is 10s, got 1m40s",
 		},
 		{
 			name: "too many retries",
@@ -120,6 +132,18 @@ func TestValidate(t *testing.T) {
 			},
 			err: "request[0]: all OIDs must begin with a dot, got: 4.5.6",
 		},
+		{
+			name: "non-numeric OID",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType: snmpproxy.Get,
+						Oids:        []string{".1.2.3", ".1.3.6foo"},
+					},
+				},
+			},
+			err: "request[0]: all OIDs must consist of numbers separated by dots, got: .1.3.6foo",
+		},
 		{
 			name: "missing maxRepetitions",
 			request: &snmpproxy.ApiRequest{
@@ -133,6 +157,100 @@ func TestValidate(t *testing.T) {
 			err: "request[0]: field max_repetitions is required for RequestType = Walk, " +
 				"and it mustn't be zero, oid: .1.2.3",
 		},
+		{
+			name: "max results with get",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType: snmpproxy.Get,
+						Oids:        []string{".1.2.3"},
+						MaxResults:  10,
+					},
+				},
+			},
+			err: "request[0]: fields max_results and cursor are only supported with RequestType = Walk",
+		},
+		{
+			name: "parallel get",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType: snmpproxy.Get,
+						Oids:        []string{".1.2.3"},
+						Parallel:    2,
+					},
+				},
+			},
+			err: "request[0]: field parallel is only supported with RequestType = Walk",
+		},
+		{
+			name: "too parallel walk",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType:    snmpproxy.Walk,
+						Oids:           []string{".1.2.3"},
+						MaxRepetitions: 10,
+						Parallel:       5,
+					},
+				},
+			},
+			err: "request[0]: maximum allowed parallelism is 4, got 5",
+		},
+		{
+			name: "walk strategy with get",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType:  snmpproxy.Get,
+						Oids:         []string{".1.2.3"},
+						WalkStrategy: snmpproxy.WalkStrategyAuto,
+					},
+				},
+			},
+			err: "request[0]: field walk_strategy is only supported with RequestType = Walk",
+		},
+		{
+			name: "unknown walk strategy",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType:    snmpproxy.Walk,
+						Oids:           []string{".1.2.3"},
+						MaxRepetitions: 10,
+						WalkStrategy:   "fast",
+					},
+				},
+			},
+			err: `request[0]: unknown walk strategy "fast", supported are: bulk, getnext, auto`,
+		},
+		{
+			name: "partial results with get",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType:    snmpproxy.GetNext,
+						Oids:           []string{".1.2.3"},
+						PartialResults: true,
+					},
+				},
+			},
+			err: "request[0]: field partial_results is only supported with RequestType = Walk",
+		},
+		{
+			name: "cursor of a different walk",
+			request: &snmpproxy.ApiRequest{
+				Requests: []snmpproxy.Request{
+					{
+						RequestType:    snmpproxy.Walk,
+						Oids:           []string{".1.2.3"},
+						MaxRepetitions: 10,
+						Cursor:         &snmpproxy.WalkCursor{Root: ".1.2.4", Oid: ".1.2.4.5"},
+					},
+				},
+			},
+			err: "request[0]: cursor belongs to a walk of a different OID .1.2.4, expected .1.2.3",
+		},
 		{
 			name: "error in one of the requests",
 			request: &snmpproxy.ApiRequest{
@@ -150,10 +268,77 @@ func TestValidate(t *testing.T) {
 			},
 			err: "request[1]: all OIDs must begin with a dot, got: 7.8.9",
 		},
+		{
+			name: "retries take too long with exponential backoff",
+			request: &snmpproxy.ApiRequest{
+				Timeout:     10 * time.Second,
+				Retries:     3,
+				RetryPolicy: &snmpproxy.RetryPolicy{Backoff: snmpproxy.BackoffExponential},
+				Requests:    []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+			err: "a request with 3 retries may take up to 2m30s with the retry policy, maximum allowed is 1m50s",
+		},
+		{
+			name: "no error, capped exponential backoff",
+			request: &snmpproxy.ApiRequest{
+				Timeout: 10 * time.Second,
+				Retries: 10,
+				RetryPolicy: &snmpproxy.RetryPolicy{
+					Backoff:    snmpproxy.BackoffExponential,
+					MaxTimeout: 10 * time.Second,
+				},
+				Requests: []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+		},
+		{
+			name: "retries take too long with jitter of the profile",
+			request: &snmpproxy.ApiRequest{
+				Timeout:  10 * time.Second,
+				Retries:  10,
+				Profile:  "jittery",
+				Requests: []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+			err: "a request with 10 retries may take up to 2m45s with the retry policy, maximum allowed is 1m50s",
+		},
+		{
+			name: "retries take too long with jitter of the profile in different case",
+			request: &snmpproxy.ApiRequest{
+				Timeout:  10 * time.Second,
+				Retries:  10,
+				Profile:  "Jittery",
+				Requests: []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+			err: "a request with 10 retries may take up to 2m45s with the retry policy, maximum allowed is 1m50s",
+		},
+		{
+			name: "unknown backoff",
+			request: &snmpproxy.ApiRequest{
+				RetryPolicy: &snmpproxy.RetryPolicy{Backoff: "linear"},
+				Requests:    []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+			err: `invalid retry_policy: unknown backoff "linear", supported are: fixed, exponential`,
+		},
+		{
+			name: "too large jitter",
+			request: &snmpproxy.ApiRequest{
+				RetryPolicy: &snmpproxy.RetryPolicy{Jitter: 1},
+				Requests:    []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			},
+			err: "invalid retry_policy: jitter must be at least 0 and less than 1, got 1",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			validator := snmpproxy.NewRequestValidator(10, 10)
+			validator.SetMaxWalkParallelism(4)
+			validator.SetRetryPolicies(snmpproxy.RetryPolicies{
+				Profiles: map[string]snmpproxy.RetryPolicy{"jittery": {Jitter: 0.5}},
+			})
+
+			// the timeout only matters to the rows that set it, the others need a valid one
+			if test.request.Timeout == 0 {
+				test.request.Timeout = time.Second
+			}
 
 			err := validator.Validate(test.request)
 
@@ -165,3 +350,42 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMinTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		minTimeout time.Duration // not set if zero
+		timeout    time.Duration
+		err        string
+	}{
+		{name: "below default", timeout: time.Nanosecond, err: "minimum allowed timeout is 10ms, got 1ns"},
+		{name: "default", timeout: 10 * time.Millisecond},
+		{name: "below set", minTimeout: 10 * time.Millisecond, timeout: 5 * time.Millisecond,
+			err: "minimum allowed timeout is 10ms, got 5ms"},
+		{name: "set", minTimeout: 10 * time.Millisecond, timeout: 10 * time.Millisecond},
+		{name: "above set", minTimeout: 10 * time.Millisecond, timeout: 250 * time.Millisecond},
+		{name: "set below default", minTimeout: time.Millisecond, timeout: 5 * time.Millisecond,
+			err: "minimum allowed timeout is 10ms, got 5ms"},
+		{name: "below higher set", minTimeout: 100 * time.Millisecond, timeout: 50 * time.Millisecond,
+			err: "minimum allowed timeout is 100ms, got 50ms"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validator := snmpproxy.NewRequestValidator(10, 10)
+			if test.minTimeout != 0 {
+				validator.SetMinTimeout(test.minTimeout)
+			}
+
+			err := validator.Validate(&snmpproxy.ApiRequest{
+				Timeout:  test.timeout,
+				Requests: []snmpproxy.Request{{RequestType: snmpproxy.Get, Oids: []string{".1.2.3"}}},
+			})
+
+			if test.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -2,9 +2,14 @@ package snmpproxy
 
 import (
 	"encoding/binary"
+	"math/big"
+	"net"
+	"net/netip"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"unicode"
+	"unicode/utf16"
 	"unicode/utf8"
 	"unsafe"
 
@@ -13,15 +18,61 @@ import (
 )
 
 type ValueFormatter struct {
-	mibDataProvider *mib.DataProvider
+	mibDataProvider atomic.Pointer[mib.DataProvider]
+	formatOverrides atomic.Pointer[FormatOverrides]
+}
+
+// SetMibDataProvider atomically replaces the MIB data used for formatting; it's safe to call it at any time.
+func (f *ValueFormatter) SetMibDataProvider(mibDataProvider *mib.DataProvider) {
+	f.mibDataProvider.Store(mibDataProvider)
+}
+
+// SetFormatOverrides atomically replaces the user-defined format overrides; it's safe to call it at any time.
+func (f *ValueFormatter) SetFormatOverrides(formatOverrides *FormatOverrides) {
+	f.formatOverrides.Store(formatOverrides)
 }
 
 func (f *ValueFormatter) Format(dataUnit gosnmp.SnmpPDU) any {
+	return f.FormatForProfile("", dataUnit)
+}
+
+// FormatForProfile formats the value while taking into account the format overrides of the given device profile.
+func (f *ValueFormatter) FormatForProfile(profile string, dataUnit gosnmp.SnmpPDU) any {
+	return f.snapshot(profile).format(dataUnit)
+}
+
+// snapshot returns the formatter of the values of the device profile using the current MIB data and format
+// overrides, so that all the values of a request are formatted alike even if those are replaced meanwhile.
+func (f *ValueFormatter) snapshot(profile string) *formatSnapshot {
+	return &formatSnapshot{
+		formatter:       f,
+		profile:         profile,
+		mibDataProvider: f.mibDataProvider.Load(),
+		formatOverrides: f.formatOverrides.Load(),
+	}
+}
+
+type formatSnapshot struct {
+	formatter       *ValueFormatter
+	profile         string
+	mibDataProvider *mib.DataProvider
+	formatOverrides *FormatOverrides
+}
+
+func (s *formatSnapshot) format(dataUnit gosnmp.SnmpPDU) any {
+	f := s.formatter
+
+	if override, ok := s.formatOverrides.Get(s.profile, dataUnit.Name); ok {
+		if value, ok := f.formatOverride(override, dataUnit); ok {
+			return value
+		}
+	}
+
 	if dataUnit.Type != gosnmp.OctetString {
 		return dataUnit.Value
 	}
 
-	switch f.mibDataProvider.GetDisplayHint(dataUnit.Name) {
+	switch s.mibDataProvider.GetDisplayHint(dataUnit.Name) {
 	case mib.DisplayHintString:
 		return f.getValueAsString(dataUnit.Value.([]byte))
 	case mib.DisplayHintDateAndTime:
@@ -33,26 +84,134 @@ func (f *ValueFormatter) Format(dataUnit gosnmp.SnmpPDU) any {
 
 		fallthrough
 	default:
-		value := dataUnit.Value.([]byte)
-		result := make([]byte, len(value)*3-1) // 2 chars per byte + space separator between each (hence -1)
+		return f.formatHex(dataUnit.Value.([]byte), ' ')
+	}
+}
+
+// formatOverride returns false if the value can't be formatted using the override (e.g. it has an unexpected type
+// or size), in which case the value should be formatted as if there was no override.
+func (f *ValueFormatter) formatOverride(override FormatOverride, dataUnit gosnmp.SnmpPDU) (any, bool) {
+	if override.Format == OverrideFormatInteger {
+		return f.formatInteger(dataUnit.Value, override.Scale)
+	}
+
+	value, ok := dataUnit.Value.([]byte)
+	if !ok {
+		return nil, false
+	}
+
+	switch override.Format {
+	case OverrideFormatString:
+		return f.getValueAsString(value), true
+	case OverrideFormatHex:
+		return f.formatHex(value, ' '), true
+	case OverrideFormatMac:
+		return f.formatHex(value, ':'), true
+	case OverrideFormatIpv4:
+		if len(value) != net.IPv4len {
+			return nil, false
+		}
+
+		return netip.AddrFrom4([net.IPv4len]byte(value)).String(), true
+	case OverrideFormatDateTime:
+		if len(value) != 8 && len(value) != 11 {
+			return nil, false
+		}
+
+		return f.formatDateAndTime(value), true
+	case OverrideFormatUtf16:
+		return f.formatUtf16(value)
+	default:
+		return nil, false
+	}
+}
+
+func (f *ValueFormatter) formatHex(value []byte, separator byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	result := make([]byte, len(value)*3-1) // 2 chars per byte + separator between each (hence -1)
+
+	const hexTable = "0123456789ABCDEF"
+
+	var j int
+
+	for i, v := range value {
+		if i != 0 {
+			result[j] = separator
+			j++
+		}
+
+		result[j] = hexTable[v>>4]
+		result[j+1] = hexTable[v&0x0f]
+		j += 2
+	}
+
+	return f.getValueAsString(result)
+}
+
+func (*ValueFormatter) formatInteger(value any, scale float64) (any, bool) {
+	var integer *big.Int
+
+	switch v := value.(type) {
+	case int:
+		integer = big.NewInt(int64(v))
+	case int64:
+		integer = big.NewInt(v)
+	case uint:
+		integer = new(big.Int).SetUint64(uint64(v))
+	case uint32:
+		integer = new(big.Int).SetUint64(uint64(v))
+	case uint64:
+		integer = new(big.Int).SetUint64(v)
+	case []byte: // some devices encode integers as (big-endian) octet strings
+		if len(v) > 8 {
+			return nil, false
+		}
+
+		integer = new(big.Int).SetBytes(v)
+	default:
+		return nil, false
+	}
 
-		const hexTable = "0123456789ABCDEF"
+	if scale != 0 {
+		result, _ := new(big.Float).Mul(new(big.Float).SetInt(integer), big.NewFloat(scale)).Float64()
 
-		var j int
+		return result, true
+	}
 
-		for i, v := range value {
-			if i != 0 {
-				result[j] = ' '
-				j++
-			}
+	if integer.IsInt64() {
+		return integer.Int64(), true
+	}
 
-			result[j] = hexTable[v>>4]
-			result[j+1] = hexTable[v&0x0f]
-			j += 2
+	return integer.Uint64(), true
+}
+
+func (*ValueFormatter) formatUtf16(value []byte) (string, bool) {
+	if len(value)%2 != 0 {
+		return "", false
+	}
+
+	var byteOrder binary.ByteOrder = binary.BigEndian
+
+	// respect the byte order mark, if there is any
+	if len(value) >= 2 {
+		switch {
+		case value[0] == 0xFE && value[1] == 0xFF:
+			value = value[2:]
+		case value[0] == 0xFF && value[1] == 0xFE:
+			value = value[2:]
+			byteOrder = binary.LittleEndian
 		}
+	}
 
-		return f.getValueAsString(result)
+	codeUnits := make([]uint16, len(value)/2)
+	for i := range codeUnits {
+		codeUnits[i] = byteOrder.Uint16(value[i*2:])
 	}
+
+	return string(utf16.Decode(codeUnits)), true
 }
 
 func (*ValueFormatter) formatDateAndTime(value []byte) string {
@@ -124,5 +283,8 @@ func (*ValueFormatter) isStringPrintable(value []byte) bool {
 }
 
 func NewValueFormatter(mibDataProvider *mib.DataProvider) *ValueFormatter {
-	return &ValueFormatter{mibDataProvider: mibDataProvider}
+	formatter := &ValueFormatter{}
+	formatter.SetMibDataProvider(mibDataProvider)
+
+	return formatter
 }
@@ -1,6 +1,7 @@
 package snmpproxy_test
 
 import (
+	"strconv"
 	"testing"
 
 	"github.com/gosnmp/gosnmp"
@@ -12,10 +13,10 @@ import (
 func TestValueFormatter_Format(t *testing.T) {
 	formatter := snmpproxy.NewValueFormatter(
 		mib.NewDataProvider(
-			map[string]mib.DisplayHint{
-				".1.3.6.3": mib.DisplayHintString,
-				".1.3.6.4": mib.DisplayHintHexadecimal,
-				".1.3.6.5": mib.DisplayHintDateAndTime,
+			mib.Objects{
+				".1.3.6.3": {DisplayHint: mib.DisplayHintString},
+				".1.3.6.4": {DisplayHint: mib.DisplayHintHexadecimal},
+				".1.3.6.5": {DisplayHint: mib.DisplayHintDateAndTime},
 			},
 		),
 	)
@@ -80,3 +81,164 @@ func TestValueFormatter_Format(t *testing.T) {
 		})
 	}
 }
+
+func TestValueFormatter_FormatWithOverrides(t *testing.T) {
+	formatter := snmpproxy.NewValueFormatter(
+		mib.NewDataProvider(mib.Objects{".1.3.6.3": {DisplayHint: mib.DisplayHintString}}),
+	)
+
+	overrides, err := snmpproxy.NewFormatOverrides(
+		[]snmpproxy.FormatOverride{
+			{Prefix: ".1.3.6.3", Format: snmpproxy.OverrideFormatHex},
+			{Prefix: ".1.3.6.4", Format: snmpproxy.OverrideFormatString},
+			{Prefix: ".1.3.6.5", Format: snmpproxy.OverrideFormatMac},
+			{Prefix: ".1.3.6.6", Format: snmpproxy.OverrideFormatIpv4},
+			{Prefix: ".1.3.6.7", Format: snmpproxy.OverrideFormatDateTime},
+			{Prefix: ".1.3.6.8", Format: snmpproxy.OverrideFormatUtf16},
+			{Prefix: ".1.3.6.9", Format: snmpproxy.OverrideFormatInteger},
+			{Prefix: ".1.3.6.10", Format: snmpproxy.OverrideFormatInteger, Scale: 0.1},
+		},
+		map[string][]snmpproxy.FormatOverride{
+			"vendor": {{Prefix: ".1.3.6.3.1", Format: snmpproxy.OverrideFormatString}},
+		},
+	)
+	require.NoError(t, err)
+
+	formatter.SetFormatOverrides(overrides)
+
+	tests := []struct {
+		name     string
+		profile  string
+		pdu      gosnmp.SnmpPDU
+		expected any
+	}{
+		{
+			name:     "override takes precedence over MIB",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.3.1", Type: gosnmp.OctetString, Value: []byte{'a', 'b'}},
+			expected: "61 62",
+		},
+		{
+			name:     "profile override",
+			profile:  "vendor",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.3.1", Type: gosnmp.OctetString, Value: []byte{'a', 'b'}},
+			expected: "ab",
+		},
+		{
+			name:     "string",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.4.1", Type: gosnmp.OctetString, Value: []byte{'a', 0, 'b'}},
+			expected: "a\x00b",
+		},
+		{
+			name:     "mac",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.5.1", Type: gosnmp.OctetString, Value: []byte("ST\x00_A\xd0")},
+			expected: "53:54:00:5F:41:D0",
+		},
+		{
+			name:     "empty mac",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.5.1", Type: gosnmp.OctetString, Value: []byte{}},
+			expected: "",
+		},
+		{
+			name:     "ipv4",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.6.1", Type: gosnmp.OctetString, Value: []byte{10, 0, 1, 254}},
+			expected: "10.0.1.254",
+		},
+		{
+			name:     "ipv4 with unexpected length falls back",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.6.1", Type: gosnmp.OctetString, Value: []byte{10, 0, 1}},
+			expected: "0A 00 01",
+		},
+		{
+			name: "datetime",
+			pdu: gosnmp.SnmpPDU{
+				Name:  ".1.3.6.7.1",
+				Type:  gosnmp.OctetString,
+				Value: []byte{0o7, 229, 10, 15, 14, 56, 8, 0o0},
+			},
+			expected: "2021-10-15,14:56:8.0",
+		},
+		{
+			name:     "datetime with unexpected length falls back",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.7.1", Type: gosnmp.OctetString, Value: []byte{0o7, 229}},
+			expected: "07 E5",
+		},
+		{
+			name:     "utf16",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.8.1", Type: gosnmp.OctetString, Value: []byte{0, 'a', 0x01, 0x0D}},
+			expected: "ač",
+		},
+		{
+			name:     "utf16 little endian",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.8.1", Type: gosnmp.OctetString, Value: []byte{0xFF, 0xFE, 'a', 0}},
+			expected: "a",
+		},
+		{
+			name:     "utf16 with odd length falls back",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.8.1", Type: gosnmp.OctetString, Value: []byte{0, 'a', 'b'}},
+			expected: "00 61 62",
+		},
+		{
+			name:     "integer from octet string",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.9.1", Type: gosnmp.OctetString, Value: []byte{0x01, 0x00}},
+			expected: int64(256),
+		},
+		{
+			name:     "integer from large counter",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.9.1", Type: gosnmp.Counter64, Value: uint64(1 << 63)},
+			expected: uint64(1 << 63),
+		},
+		{
+			name:     "integer with scale",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.10.1", Type: gosnmp.Gauge32, Value: uint(235)},
+			expected: 23.5,
+		},
+		{
+			name:     "integer with unexpected type falls back",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.10.1", Type: gosnmp.ObjectIdentifier, Value: ".1.2.3"},
+			expected: ".1.2.3",
+		},
+		{
+			name:     "format for non-octet string falls back",
+			pdu:      gosnmp.SnmpPDU{Name: ".1.3.6.5.1", Type: gosnmp.Integer, Value: 123},
+			expected: 123,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, formatter.FormatForProfile(test.profile, test.pdu))
+		})
+	}
+}
+
+func BenchmarkValueFormatter_FormatWalk(b *testing.B) {
+	formatter := snmpproxy.NewValueFormatter(
+		mib.NewDataProvider(
+			mib.Objects{
+				".1.3.6.1.2.1.2.2.1.2":  {Name: "ifDescr", DisplayHint: mib.DisplayHintString},
+				".1.3.6.1.2.1.2.2.1.6":  {Name: "ifPhysAddress", DisplayHint: mib.DisplayHintHexadecimal},
+				".1.3.6.1.2.1.4.22.1.2": {Name: "ipNetToMediaPhysAddress", DisplayHint: mib.DisplayHintHexadecimal},
+			},
+		),
+	)
+
+	pdus := make([]gosnmp.SnmpPDU, 0, 3000)
+	for i := range 1000 {
+		index := strconv.Itoa(i)
+		pdus = append(
+			pdus,
+			gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.2." + index, Type: gosnmp.OctetString, Value: []byte("Ethernet1")},
+			gosnmp.SnmpPDU{Name: ".1.3.6.1.2.1.2.2.1.6." + index, Type: gosnmp.OctetString, Value: []byte{1, 2, 3, 4, 5, 6}},
+			gosnmp.SnmpPDU{
+				Name:  ".1.3.6.1.2.1.4.22.1.2.2000955.185.152.67." + index,
+				Type:  gosnmp.OctetString,
+				Value: []byte{1, 2, 3, 4, 5, 6},
+			},
+		)
+	}
+
+	b.ResetTimer()
+
+	for i := range b.N {
+		formatter.Format(pdus[i%len(pdus)])
+	}
+}
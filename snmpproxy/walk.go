@@ -0,0 +1,180 @@
+package snmpproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// defaultMaxRepetitions is used when there are no max repetitions set, the same as in gosnmp.
+const defaultMaxRepetitions = 50
+
+// errWalkStopped may be returned by the gosnmp.WalkFunc to stop the walk without an error.
+var errWalkStopped = errors.New("walk stopped")
+
+// walk calls the function for every varbind in the subtree of the root OID (using either GetNext or GetBulk, based
+// on the settings), starting right after the given OID. That's either the root itself, or an OID from the subtree when
+// continuing a previous walk. Apart from that, it works the same way as the walk of gosnmp.
+func (h *snmpHandler) walk(root string, from string, settings walkSettings, fn gosnmp.WalkFunc) error {
+	if root == "" || root == "." {
+		root, from = ".1.3.6.1.2.1", ".1.3.6.1.2.1"
+	}
+
+	maxRepetitions := settings.maxRepetitions
+	if maxRepetitions == 0 {
+		maxRepetitions = defaultMaxRepetitions
+	}
+
+	oid, last := from, from
+
+	for requests := 1; ; requests++ {
+		var (
+			response *gosnmp.SnmpPacket
+			err      error
+		)
+
+		if settings.getNext {
+			response, err = h.GetNext([]string{oid})
+		} else {
+			response, maxRepetitions, err = h.getBulk(root, oid, maxRepetitions, settings.tuner)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		// any error status (e.g. NoSuchName with SNMP v1) means there is nothing more to walk
+		if len(response.Variables) == 0 || response.Error != gosnmp.NoError {
+			return nil
+		}
+
+		for i, dataUnit := range response.Variables {
+			switch dataUnit.Type {
+			case gosnmp.EndOfMibView, gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+				return nil
+			}
+
+			if !strings.HasPrefix(dataUnit.Name, root+".") {
+				// the root might be a leaf, which can't be found by GetNext
+				if requests == 1 && i == 0 && from == root {
+					return h.walkLeaf(root, fn)
+				}
+
+				return nil
+			}
+
+			// a broken agent might return the same OIDs over and over again
+			if compareOids(dataUnit.Name, last) <= 0 {
+				return fmt.Errorf("%w: %s after %s", ErrOidNotIncreasing, dataUnit.Name, last)
+			}
+
+			last = dataUnit.Name
+
+			if err = fn(dataUnit); err != nil {
+				return err
+			}
+		}
+
+		oid = response.Variables[len(response.Variables)-1].Name
+	}
+}
+
+// getBulk requests the varbinds following the OID. If the agent responds that the response would be too big,
+// it's requested again with lower max repetitions. The tuner, if given, learns from every response, and from its
+// round trip only (see snmpHandler.latency). It returns the max repetitions to use for the next request.
+func (h *snmpHandler) getBulk(
+	root string,
+	oid string,
+	maxRepetitions uint32,
+	tuner *repetitionsTuner,
+) (*gosnmp.SnmpPacket, uint32, error) {
+	for {
+		requested := maxRepetitions
+		response, err := h.GetBulk([]string{oid}, 0, requested)
+
+		// the cancelled requests say nothing about the agent, and neither do the ones rejected by the circuit breaker
+		if tuner != nil && h.Context.Err() == nil && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.Is(err, ErrTargetUnavailable) {
+			if err != nil {
+				response = nil
+			}
+
+			maxRepetitions = tuner.observe(requested, response, isTruncated(root, requested, response), h.latency)
+		}
+
+		if err != nil {
+			return nil, maxRepetitions, err
+		}
+
+		if response.Error != gosnmp.TooBig || requested == 1 {
+			return response, maxRepetitions, nil
+		}
+
+		if tuner == nil {
+			maxRepetitions = requested / 2
+		}
+	}
+}
+
+// isTruncated tells whether the agent returned fewer varbinds than requested, even though the walk isn't finished.
+func isTruncated(root string, requested uint32, response *gosnmp.SnmpPacket) bool {
+	if response == nil || response.Error != gosnmp.NoError || len(response.Variables) == 0 ||
+		len(response.Variables) >= int(requested) {
+		return false
+	}
+
+	switch last := response.Variables[len(response.Variables)-1]; last.Type {
+	case gosnmp.EndOfMibView, gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+		return false
+	default:
+		return strings.HasPrefix(last.Name, root+".")
+	}
+}
+
+func (h *snmpHandler) walkLeaf(oid string, fn gosnmp.WalkFunc) error {
+	response, err := h.Get([]string{oid})
+	if err != nil {
+		return err
+	}
+
+	if len(response.Variables) == 0 || response.Error != gosnmp.NoError {
+		return nil
+	}
+
+	switch dataUnit := response.Variables[0]; dataUnit.Type {
+	case gosnmp.EndOfMibView, gosnmp.NoSuchObject, gosnmp.NoSuchInstance:
+		return nil
+	default:
+		if dataUnit.Name != oid {
+			return nil
+		}
+
+		return fn(dataUnit)
+	}
+}
+
+// compareOids compares the OIDs lexicographically by their sub-identifiers.
+func compareOids(a, b string) int {
+	a, b = strings.TrimPrefix(a, "."), strings.TrimPrefix(b, ".")
+
+	for a != "" && b != "" {
+		var aPart, bPart string
+
+		aPart, a, _ = strings.Cut(a, ".")
+		bPart, b, _ = strings.Cut(b, ".")
+
+		// without leading zeros, the longer number is the larger one
+		if len(aPart) != len(bPart) {
+			return len(aPart) - len(bPart)
+		}
+
+		if c := strings.Compare(aPart, bPart); c != 0 {
+			return c
+		}
+	}
+
+	return len(a) - len(b)
+}